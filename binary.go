@@ -0,0 +1,158 @@
+package httphandler
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// binaryWriter is implemented by the writers of the binary formats (MessagePack and CBOR).
+type binaryWriter interface {
+	writeNil()
+	writeBool(v bool)
+	writeInt(v int64)
+	writeUint(v uint64)
+	writeFloat(v float64)
+	writeString(v string)
+	writeArrayHeader(n int)
+	writeMapHeader(n int)
+	writeRaw(v []byte)
+	bytes() []byte
+}
+
+// rawBinaryValue is an already encoded value that is written as is.
+type rawBinaryValue []byte
+
+// encodeBinaryWireError encodes the WireError as map with the keys StatusCode, Error and RequestUUID (the same keys
+// DefaultJSONEncoder uses).
+// marshal reports whether the Error implements the marshaler interface of the format, in that case the value it
+// returns is used as is. Otherwise the Error is marshaled first (using its JSON representation) and if the marshaled
+// error is empty the Error() function is used.
+func encodeBinaryWireError(bw binaryWriter, e *WireError, marshal func(error) ([]byte, bool, error)) ([]byte, error) {
+	var errorValue interface{}
+	if e.Error != nil {
+		raw, ok, err := marshal(e.Error)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to encode error")
+		}
+		if ok {
+			errorValue = rawBinaryValue(raw)
+		} else {
+			errorValue, err = genericError(e.Error)
+			if err != nil {
+				return nil, errors.Wrap(err, "unable to encode error")
+			}
+		}
+	}
+
+	bw.writeMapHeader(3) //nolint:gomnd // StatusCode, Error and RequestUUID
+	bw.writeString("StatusCode")
+	bw.writeInt(int64(e.StatusCode))
+	bw.writeString("Error")
+	if err := encodeBinaryValue(bw, errorValue); err != nil {
+		return nil, errors.Wrap(err, "unable to encode error")
+	}
+	bw.writeString("RequestUUID")
+	bw.writeString(e.RequestUUID)
+	return bw.bytes(), nil
+}
+
+// genericError returns the generic representation of the error (see genericValue), if the representation is empty the
+// Error() function is used.
+func genericError(e error) (interface{}, error) {
+	value, err := genericValue(e)
+	if err != nil {
+		return nil, err
+	}
+	switch v := value.(type) {
+	case nil:
+		return e.Error(), nil
+	case map[string]interface{}:
+		if len(v) == 0 {
+			return e.Error(), nil
+		}
+	}
+	return value, nil
+}
+
+// encodeBinaryValue encodes a generic value (as returned by genericValue) using the binaryWriter.
+// Map keys are encoded in sorted order, so the output is stable.
+func encodeBinaryValue(bw binaryWriter, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		bw.writeNil()
+	case bool:
+		bw.writeBool(v)
+	case string:
+		bw.writeString(v)
+	case json.Number:
+		return encodeBinaryNumber(bw, v)
+	case rawBinaryValue:
+		bw.writeRaw(v)
+	case []interface{}:
+		bw.writeArrayHeader(len(v))
+		for _, item := range v {
+			if err := encodeBinaryValue(bw, item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		bw.writeMapHeader(len(keys))
+		for _, key := range keys {
+			bw.writeString(key)
+			if err := encodeBinaryValue(bw, v[key]); err != nil {
+				return err
+			}
+		}
+	default:
+		return errors.Errorf("unsupported type %T", value)
+	}
+	return nil
+}
+
+// encodeBinaryNumber encodes the number as integer if possible, otherwise as float.
+func encodeBinaryNumber(bw binaryWriter, n json.Number) error {
+	if i, err := n.Int64(); err == nil {
+		bw.writeInt(i)
+		return nil
+	}
+	if u, err := strconv.ParseUint(string(n), 10, 64); err == nil {
+		bw.writeUint(u)
+		return nil
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return errors.Wrapf(err, "invalid number %q", n)
+	}
+	bw.writeFloat(f)
+	return nil
+}
+
+// appendUint16 appends v in big endian byte order.
+func appendUint16(buf []byte, v uint16) []byte {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], v)
+	return append(buf, b[:]...)
+}
+
+// appendUint32 appends v in big endian byte order.
+func appendUint32(buf []byte, v uint32) []byte {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	return append(buf, b[:]...)
+}
+
+// appendUint64 appends v in big endian byte order.
+func appendUint64(buf []byte, v uint64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	return append(buf, b[:]...)
+}
@@ -0,0 +1,141 @@
+package httphandler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Eun/go-hit"
+
+	"github.com/pkg/errors"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/talon-one/go-httphandler"
+)
+
+type binaryError struct {
+	Code  int
+	Next  *int
+	Ratio float64
+	Tags  []string
+	Valid bool
+}
+
+func (e binaryError) Error() string {
+	return "binary error"
+}
+
+type rawBinaryError struct{}
+
+func (rawBinaryError) Error() string {
+	return "raw error"
+}
+
+func (rawBinaryError) MarshalMsgpack() ([]byte, error) {
+	return []byte{0xa3, 'r', 'a', 'w'}, nil
+}
+
+func (rawBinaryError) MarshalCBOR() ([]byte, error) {
+	return []byte{0x63, 'r', 'a', 'w'}, nil
+}
+
+func TestBinaryEncoders(t *testing.T) {
+	encode := func(encoder httphandler.EncodeFunc, err error) []byte {
+		w := httptest.NewRecorder()
+		require.NoError(t, encoder(w, nil, &httphandler.WireError{
+			StatusCode:  http.StatusBadRequest,
+			Error:       err,
+			RequestUUID: "1",
+		}))
+		return w.Body.Bytes()
+	}
+	// wireError builds the expected output for a WireError with the status code 400 and the request uuid "1",
+	// the prefixes are the encoded keys and status code of the format.
+	wireError := func(head, statusCode, errorKey, requestUUIDKey []byte, errorValue ...byte) []byte {
+		buf := append(append([]byte{}, head...), statusCode...)
+		buf = append(append(buf, errorKey...), errorValue...)
+		return append(append(buf, requestUUIDKey...), '1')
+	}
+	msgpack := func(errorValue ...byte) []byte {
+		return wireError(
+			append([]byte{0x83, 0xaa}, "StatusCode"...),
+			[]byte{0xcd, 0x01, 0x90},
+			append([]byte{0xa5}, "Error"...),
+			append([]byte{0xab}, "RequestUUID\xa1"...),
+			errorValue...,
+		)
+	}
+	cbor := func(errorValue ...byte) []byte {
+		return wireError(
+			append([]byte{0xa3, 0x6a}, "StatusCode"...),
+			[]byte{0x19, 0x01, 0x90},
+			append([]byte{0x65}, "Error"...),
+			append([]byte{0x6b}, "RequestUUID\x61"...),
+			errorValue...,
+		)
+	}
+	structError := binaryError{Code: -1, Ratio: 0.5, Tags: []string{"a"}, Valid: true}
+
+	t.Run("msgpack", func(t *testing.T) {
+		encoder := httphandler.DefaultMsgpackEncoder()
+		require.Equal(t, msgpack(0xa1, 'x'), encode(encoder, errors.New("x")))
+		require.Equal(t, msgpack(0xa3, 'r', 'a', 'w'), encode(encoder, rawBinaryError{}))
+		require.Equal(t, msgpack(
+			0x85,
+			0xa4, 'C', 'o', 'd', 'e', 0xff,
+			0xa4, 'N', 'e', 'x', 't', 0xc0,
+			0xa5, 'R', 'a', 't', 'i', 'o', 0xcb, 0x3f, 0xe0, 0, 0, 0, 0, 0, 0,
+			0xa4, 'T', 'a', 'g', 's', 0x91, 0xa1, 'a',
+			0xa5, 'V', 'a', 'l', 'i', 'd', 0xc3,
+		), encode(encoder, structError))
+	})
+
+	t.Run("cbor", func(t *testing.T) {
+		encoder := httphandler.DefaultCBOREncoder()
+		require.Equal(t, cbor(0x61, 'x'), encode(encoder, errors.New("x")))
+		require.Equal(t, cbor(0x63, 'r', 'a', 'w'), encode(encoder, rawBinaryError{}))
+		require.Equal(t, cbor(
+			0xa5,
+			0x64, 'C', 'o', 'd', 'e', 0x20,
+			0x64, 'N', 'e', 'x', 't', 0xf6,
+			0x65, 'R', 'a', 't', 'i', 'o', 0xfb, 0x3f, 0xe0, 0, 0, 0, 0, 0, 0,
+			0x64, 'T', 'a', 'g', 's', 0x81, 0x61, 'a',
+			0x65, 'V', 'a', 'l', 'i', 'd', 0xf5,
+		), encode(encoder, structError))
+	})
+
+	t.Run("negotiation", func(t *testing.T) {
+		h := httphandler.New(nil)
+		require.NoError(t, h.SetRequestUUIDFunc(func() string {
+			return "1"
+		}))
+		require.NoError(t, h.SetEncoder("application/msgpack", httphandler.DefaultMsgpackEncoder()))
+		require.NoError(t, h.SetEncoder("application/cbor", httphandler.DefaultCBOREncoder()))
+		s := httptest.NewServer(h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+			return &httphandler.HandlerError{
+				StatusCode:  http.StatusBadRequest,
+				PublicError: errors.New("x"),
+			}
+		}))
+		defer s.Close()
+
+		hit.Test(t,
+			hit.Get(s.URL),
+			hit.Send().Headers("Accept").Add("application/msgpack"),
+			hit.Expect().Headers("Content-Type").Equal("application/msgpack"),
+			hit.Expect().Body().Bytes().Equal(msgpack(0xa1, 'x')),
+		)
+		hit.Test(t,
+			hit.Get(s.URL),
+			hit.Send().Headers("Accept").Add("application/vnd.talon+cbor"),
+			hit.Expect().Headers("Content-Type").Equal("application/vnd.talon+cbor"),
+			hit.Expect().Body().Bytes().Equal(cbor(0x61, 'x')),
+		)
+		hit.Test(t,
+			hit.Get(s.URL),
+			hit.Send().Headers("Accept").Add("*/*"),
+			hit.Expect().Headers("Content-Type").Equal("application/json"),
+		)
+	})
+}
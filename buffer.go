@@ -0,0 +1,127 @@
+package httphandler
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// maxPooledBufferSize is the capacity up to which buffers are put back into the bufferPool, larger buffers are
+// dropped so a single large response does not pin its memory.
+const maxPooledBufferSize = 64 << 10
+
+// bufferPool holds the buffers the errors are encoded into.
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	buf.Reset()
+	bufferPool.Put(buf)
+}
+
+// make sure *bufferedResponseWriter implements http.ResponseWriter.
+var _ http.ResponseWriter = &bufferedResponseWriter{}
+
+// bufferedResponseWriter collects the output of an EncodeFunc, so the response can still be changed if the encoder
+// fails. The status code is set by the Handler, calls to WriteHeader are ignored.
+type bufferedResponseWriter struct {
+	header http.Header
+	buf    *bytes.Buffer
+}
+
+func (w *bufferedResponseWriter) Header() http.Header {
+	if w.header == nil {
+		w.header = make(http.Header)
+	}
+	return w.header
+}
+
+func (w *bufferedResponseWriter) Write(b []byte) (int, error) {
+	return w.buf.Write(b)
+}
+
+func (w *bufferedResponseWriter) WriteHeader(int) {}
+
+// reset discards everything that has been written.
+func (w *bufferedResponseWriter) reset() {
+	w.header = nil
+	w.buf.Reset()
+}
+
+// encode encodes the WireError into a pooled buffer and sends it with the Content-Type and Content-Length headers.
+// If the encoder fails the failure is logged and the fallback encoder is used (unless f is the fallback encoder), if
+// that fails too a minimal plain text body is sent, so the client never receives a truncated body.
+func (h *Handler) encode(w http.ResponseWriter, r *http.Request, err *HandlerError, e *WireError,
+	f EncodeFunc, contentType string, isFallback bool) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	bw := &bufferedResponseWriter{buf: buf}
+
+	encodeErr := f(bw, r, e)
+	if encodeErr != nil {
+		h.logEncodeError(r, err, e, contentType, encodeErr)
+		bw.reset()
+		if !isFallback {
+			f, contentType = h.options.FallbackEncoderFunc()
+			contentType = strings.ToLower(contentType)
+			encodeErr = f(bw, r, e)
+			if encodeErr != nil {
+				h.logEncodeError(r, err, e, contentType, encodeErr)
+				bw.reset()
+			}
+		}
+	}
+	if encodeErr != nil {
+		contentType = "text/plain; charset=utf-8"
+		writeMinimalError(buf, e)
+	}
+
+	for key, values := range bw.header {
+		w.Header()[key] = values
+	}
+	if charset := h.options.encoderRegistry().charset(contentType); charset != "" && !strings.Contains(contentType, ";") {
+		contentType += "; charset=" + charset
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(e.StatusCode)
+	_, _ = buf.WriteTo(w)
+}
+
+func (h *Handler) logEncodeError(r *http.Request, err *HandlerError, e *WireError, contentType string, encodeErr error) {
+	h.options.LogFunc(r,
+		errors.Wrapf(encodeErr, "unable to encode %q", contentType),
+		err.InternalError,
+		err.PublicError,
+		e.StatusCode,
+		e.RequestUUID,
+	)
+}
+
+// writeMinimalError writes a plain text body that only contains the status and the RequestUUID, it is used if all
+// encoders failed.
+func writeMinimalError(buf *bytes.Buffer, e *WireError) {
+	buf.WriteString(strconv.Itoa(e.StatusCode))
+	buf.WriteByte(' ')
+	buf.WriteString(statusText(e.StatusCode))
+	if e.RequestUUID != "" {
+		buf.WriteString(" (RequestUUID: ")
+		buf.WriteString(e.RequestUUID)
+		buf.WriteByte(')')
+	}
+	buf.WriteByte('\n')
+}
@@ -0,0 +1,422 @@
+package httphandler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/pkg/errors"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PluralForm is a plural category as defined by the Unicode CLDR.
+type PluralForm string
+
+// The plural categories of the Unicode CLDR.
+const (
+	PluralZero  PluralForm = "zero"
+	PluralOne   PluralForm = "one"
+	PluralTwo   PluralForm = "two"
+	PluralFew   PluralForm = "few"
+	PluralMany  PluralForm = "many"
+	PluralOther PluralForm = "other"
+)
+
+// PluralRule returns the plural form that should be used for the count n.
+type PluralRule func(n int) PluralForm
+
+// Message is a message template of a Catalog in one language.
+// The templates use the text/template syntax, the parameters of the LocalizedError (and its Count as .Count) are
+// available in the template.
+// Messages without plural forms only need to specify Other. A Zero form is always used for a count of 0, even if the
+// plural rule of the language has no zero category.
+//
+// In JSON and YAML files a message is either a string or an object of plural forms:
+//
+//	{
+//	    "not_found": "{{.Resource}} was not found",
+//	    "retry": {"one": "retry in one second", "other": "retry in {{.Count}} seconds"}
+//	}
+type Message struct {
+	Zero  string `json:"zero,omitempty" yaml:"zero,omitempty"`
+	One   string `json:"one,omitempty" yaml:"one,omitempty"`
+	Two   string `json:"two,omitempty" yaml:"two,omitempty"`
+	Few   string `json:"few,omitempty" yaml:"few,omitempty"`
+	Many  string `json:"many,omitempty" yaml:"many,omitempty"`
+	Other string `json:"other,omitempty" yaml:"other,omitempty"`
+}
+
+// messageForms is used to decode Message without recursion.
+type messageForms Message
+
+// UnmarshalJSON decodes a message from either a string or an object of plural forms.
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*m = Message{Other: s}
+		return nil
+	}
+	return json.Unmarshal(data, (*messageForms)(m))
+}
+
+// UnmarshalYAML decodes a message from either a string or a mapping of plural forms.
+func (m *Message) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*m = Message{Other: value.Value}
+		return nil
+	}
+	return value.Decode((*messageForms)(m))
+}
+
+// form returns the template for the specified plural form.
+func (m *Message) form(form PluralForm) string {
+	switch form {
+	case PluralZero:
+		return m.Zero
+	case PluralOne:
+		return m.One
+	case PluralTwo:
+		return m.Two
+	case PluralFew:
+		return m.Few
+	case PluralMany:
+		return m.Many
+	default:
+		return m.Other
+	}
+}
+
+// compiledMessage is a Message with parsed templates.
+type compiledMessage map[PluralForm]*template.Template
+
+// template returns the template for the plural form, an explicit zero form is preferred for a count of 0.
+// If the message does not have the plural form the other form is used.
+func (m compiledMessage) template(form PluralForm, n int) *template.Template {
+	if tmpl, ok := m[PluralZero]; ok && n == 0 {
+		return tmpl
+	}
+	if tmpl, ok := m[form]; ok {
+		return tmpl
+	}
+	return m[PluralOther]
+}
+
+func compileMessage(key string, message *Message) (compiledMessage, error) {
+	compiled := make(compiledMessage)
+	for _, form := range []PluralForm{PluralZero, PluralOne, PluralTwo, PluralFew, PluralMany, PluralOther} {
+		text := message.form(form)
+		if text == "" {
+			continue
+		}
+		tmpl, err := template.New(key + "." + string(form)).Parse(text)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to parse message %q", key)
+		}
+		compiled[form] = tmpl
+	}
+	if _, ok := compiled[PluralOther]; !ok {
+		return nil, errors.Errorf("message %q has no other form", key)
+	}
+	return compiled, nil
+}
+
+// Catalog holds the messages for LocalizedErrors in multiple languages.
+// Languages are BCP 47 language tags (e.g. "en", "de-CH"), they are compared case insensitive.
+// A Catalog is safe for concurrent use.
+type Catalog struct {
+	mu               sync.RWMutex
+	fallbackLanguage string
+	messages         map[string]map[string]compiledMessage
+	fallbacks        map[string][]string
+	pluralRules      map[string]PluralRule
+}
+
+// NewCatalog creates a new Catalog.
+// The fallbackLanguage is used if none of the languages of the client has a message for the key.
+func NewCatalog(fallbackLanguage string) *Catalog {
+	return &Catalog{
+		fallbackLanguage: normalizeLanguage(fallbackLanguage),
+		messages:         make(map[string]map[string]compiledMessage),
+		fallbacks:        make(map[string][]string),
+		pluralRules:      make(map[string]PluralRule),
+	}
+}
+
+// AddMessage adds a message for the specified language and key.
+func (c *Catalog) AddMessage(language, key string, message Message) error {
+	return c.AddMessages(language, map[string]Message{key: message})
+}
+
+// AddMessages adds the messages (key and Message) for the specified language.
+func (c *Catalog) AddMessages(language string, messages map[string]Message) error {
+	language = normalizeLanguage(language)
+	if language == "" {
+		return errors.New("language cannot be empty")
+	}
+	compiled := make(map[string]compiledMessage, len(messages))
+	for key := range messages {
+		message := messages[key]
+		m, err := compileMessage(key, &message)
+		if err != nil {
+			return err
+		}
+		compiled[key] = m
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.messages[language] == nil {
+		c.messages[language] = make(map[string]compiledMessage, len(compiled))
+	}
+	for key, m := range compiled {
+		c.messages[language][key] = m
+	}
+	return nil
+}
+
+// LoadJSON adds the messages of a JSON document for the specified language.
+// The document is an object of keys and messages, see Message for the format.
+func (c *Catalog) LoadJSON(language string, data []byte) error {
+	var messages map[string]Message
+	if err := json.Unmarshal(data, &messages); err != nil {
+		return errors.Wrap(err, "unable to decode json messages")
+	}
+	return c.AddMessages(language, messages)
+}
+
+// LoadYAML adds the messages of a YAML document for the specified language.
+// The document is a mapping of keys and messages, see Message for the format.
+func (c *Catalog) LoadYAML(language string, data []byte) error {
+	var messages map[string]Message
+	if err := yaml.Unmarshal(data, &messages); err != nil {
+		return errors.Wrap(err, "unable to decode yaml messages")
+	}
+	return c.AddMessages(language, messages)
+}
+
+// LoadFS adds the messages of all files in fsys that match the pattern (see fs.Glob).
+// The language is taken from the file name (e.g. "de-CH.yaml"), the format from the extension: ".json", ".yaml" or
+// ".yml". This can be used to load catalogs that are embedded into the binary.
+//
+// Example:
+//
+//	//go:embed locales
+//	var locales embed.FS
+//
+//	catalog.LoadFS(locales, "locales/*")
+func (c *Catalog) LoadFS(fsys fs.FS, pattern string) error {
+	files, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return errors.Wrap(err, "unable to find message files")
+	}
+	for _, file := range files {
+		ext := path.Ext(file)
+		language := strings.TrimSuffix(path.Base(file), ext)
+
+		var load func(string, []byte) error
+		switch strings.ToLower(ext) {
+		case ".json":
+			load = c.LoadJSON
+		case ".yaml", ".yml":
+			load = c.LoadYAML
+		default:
+			continue
+		}
+
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return errors.Wrapf(err, "unable to read %q", file)
+		}
+		if err := load(language, data); err != nil {
+			return errors.Wrapf(err, "unable to load %q", file)
+		}
+	}
+	return nil
+}
+
+// SetFallbackLanguages sets the languages that should be tried (in order) if the specified language has no message
+// for a key, before the fallback language of the Catalog is used (e.g. "pt-BR" falls back to "pt-PT").
+// Without explicit fallbacks a language falls back to its parent language (e.g. "de-CH" to "de").
+func (c *Catalog) SetFallbackLanguages(language string, fallbacks ...string) {
+	normalized := make([]string, len(fallbacks))
+	for i, fallback := range fallbacks {
+		normalized[i] = normalizeLanguage(fallback)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fallbacks[normalizeLanguage(language)] = normalized
+}
+
+// SetPluralRule sets the plural rule for the specified language.
+// The default plural rules cover the most common languages, languages without a rule use the english rule.
+func (c *Catalog) SetPluralRule(language string, rule PluralRule) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pluralRules[normalizeLanguage(language)] = rule
+}
+
+// Languages returns the languages that have messages in the Catalog.
+func (c *Catalog) Languages() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	languages := make([]string, 0, len(c.messages))
+	for language := range c.messages {
+		languages = append(languages, language)
+	}
+	return languages
+}
+
+// Localize returns the message for the LocalizedError in the first of the specified languages that has a message for
+// the key of the error. It returns the message and the language of the message.
+func (c *Catalog) Localize(languages []string, e *LocalizedError) (message, language string, err error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, language := range c.lookupLanguages(languages) {
+		m, ok := c.messages[language][e.Key]
+		if !ok {
+			continue
+		}
+		tmpl := m.template(c.pluralForm(language, e.Count), e.Count)
+		message, err := executeMessage(tmpl, e)
+		if err != nil {
+			return "", "", err
+		}
+		return message, language, nil
+	}
+	return "", "", errors.Errorf("no message for %q", e.Key)
+}
+
+// lookupLanguages returns the languages that should be tried for the preferred languages, in order.
+func (c *Catalog) lookupLanguages(preferred []string) []string {
+	var languages []string
+	seen := make(map[string]struct{})
+	add := func(language string) {
+		if _, ok := seen[language]; ok || language == "" {
+			return
+		}
+		seen[language] = struct{}{}
+		languages = append(languages, language)
+	}
+	for _, language := range preferred {
+		language = normalizeLanguage(language)
+		for tag := language; tag != ""; tag = parentLanguage(tag) {
+			add(tag)
+			for _, fallback := range c.fallbacks[tag] {
+				add(fallback)
+			}
+		}
+	}
+	add(c.fallbackLanguage)
+	return languages
+}
+
+// pluralForm returns the plural form for the count in the specified language.
+func (c *Catalog) pluralForm(language string, n int) PluralForm {
+	for tag := language; tag != ""; tag = parentLanguage(tag) {
+		if rule, ok := c.pluralRules[tag]; ok {
+			return rule(n)
+		}
+	}
+	return defaultPluralRule(language, n)
+}
+
+func executeMessage(tmpl *template.Template, e *LocalizedError) (string, error) {
+	data := make(map[string]interface{}, len(e.Params)+1)
+	for k, v := range e.Params {
+		data[k] = v
+	}
+	data["Count"] = e.Count
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", errors.Wrapf(err, "unable to execute message %q", e.Key)
+	}
+	return buf.String(), nil
+}
+
+// normalizeLanguage returns the language tag in lower case with "-" as separator.
+func normalizeLanguage(language string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(language), "_", "-"))
+}
+
+// parentLanguage returns the language tag without its last subtag (e.g. "de" for "de-ch").
+func parentLanguage(language string) string {
+	i := strings.LastIndexByte(language, '-')
+	if i < 0 {
+		return ""
+	}
+	return language[:i]
+}
+
+// defaultPluralRule implements the CLDR plural rules for integers of the most common languages.
+//
+//nolint:gomnd // the numbers are defined by the CLDR plural rules
+func defaultPluralRule(language string, n int) PluralForm {
+	if n < 0 {
+		n = -n
+	}
+	mod10, mod100 := n%10, n%100
+	switch primaryLanguage(language) {
+	case "ja", "zh", "ko", "vi", "th", "id", "ms", "tr":
+		return PluralOther
+	case "fr", "pt", "hi":
+		if n <= 1 {
+			return PluralOne
+		}
+	case "ru", "uk", "be":
+		switch {
+		case mod10 == 1 && mod100 != 11:
+			return PluralOne
+		case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+			return PluralFew
+		default:
+			return PluralMany
+		}
+	case "pl":
+		switch {
+		case n == 1:
+			return PluralOne
+		case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+			return PluralFew
+		default:
+			return PluralMany
+		}
+	case "cs", "sk":
+		switch {
+		case n == 1:
+			return PluralOne
+		case n >= 2 && n <= 4:
+			return PluralFew
+		}
+	case "ar":
+		switch {
+		case n == 1:
+			return PluralOne
+		case n == 2:
+			return PluralTwo
+		case mod100 >= 3 && mod100 <= 10:
+			return PluralFew
+		case mod100 >= 11:
+			return PluralMany
+		}
+	default:
+		if n == 1 {
+			return PluralOne
+		}
+	}
+	return PluralOther
+}
+
+// primaryLanguage returns the primary language subtag of a language tag (e.g. "de" for "de-ch").
+func primaryLanguage(language string) string {
+	if i := strings.IndexByte(language, '-'); i >= 0 {
+		return language[:i]
+	}
+	return language
+}
@@ -0,0 +1,237 @@
+package httphandler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/Eun/go-hit"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/talon-one/go-httphandler"
+)
+
+func newTestCatalog(t *testing.T) *httphandler.Catalog {
+	catalog := httphandler.NewCatalog("en")
+	require.NoError(t, catalog.LoadFS(os.DirFS("testdata"), "locales/*"))
+	return catalog
+}
+
+func TestCatalog(t *testing.T) {
+	catalog := newTestCatalog(t)
+	require.ElementsMatch(t, []string{"en", "de", "ru"}, catalog.Languages())
+
+	tests := []struct {
+		name             string
+		languages        []string
+		err              httphandler.LocalizedError
+		expectedMessage  string
+		expectedLanguage string
+	}{
+		{
+			name:             "parameters",
+			languages:        []string{"de"},
+			err:              httphandler.LocalizedError{Key: "not_found", Params: map[string]interface{}{"Resource": "Benutzer"}},
+			expectedMessage:  "Benutzer konnte nicht gefunden werden.",
+			expectedLanguage: "de",
+		},
+		{
+			name:             "parent language",
+			languages:        []string{"de-CH"},
+			err:              httphandler.LocalizedError{Key: "items_left", Count: 3},
+			expectedMessage:  "Es sind 3 Artikel übrig.",
+			expectedLanguage: "de",
+		},
+		{
+			name:             "zero form",
+			languages:        []string{"en"},
+			err:              httphandler.LocalizedError{Key: "items_left", Count: 0},
+			expectedMessage:  "There are no items left.",
+			expectedLanguage: "en",
+		},
+		{
+			name:             "missing zero form",
+			languages:        []string{"de"},
+			err:              httphandler.LocalizedError{Key: "items_left", Count: 0},
+			expectedMessage:  "Es sind 0 Artikel übrig.",
+			expectedLanguage: "de",
+		},
+		{
+			name:             "one form",
+			languages:        []string{"en"},
+			err:              httphandler.LocalizedError{Key: "items_left", Count: 1},
+			expectedMessage:  "There is one item left.",
+			expectedLanguage: "en",
+		},
+		{
+			name:             "few form",
+			languages:        []string{"ru"},
+			err:              httphandler.LocalizedError{Key: "items_left", Count: 22},
+			expectedMessage:  "Осталось 22 товара.",
+			expectedLanguage: "ru",
+		},
+		{
+			name:             "many form",
+			languages:        []string{"ru"},
+			err:              httphandler.LocalizedError{Key: "items_left", Count: 11},
+			expectedMessage:  "Осталось 11 товаров.",
+			expectedLanguage: "ru",
+		},
+		{
+			name:             "fallback language",
+			languages:        []string{"fr", "de"},
+			err:              httphandler.LocalizedError{Key: "only_english"},
+			expectedMessage:  "This message is only available in english.",
+			expectedLanguage: "en",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			message, language, err := catalog.Localize(test.languages, &test.err)
+			require.NoError(t, err)
+			require.Equal(t, test.expectedMessage, message)
+			require.Equal(t, test.expectedLanguage, language)
+		})
+	}
+
+	t.Run("unknown key", func(t *testing.T) {
+		_, _, err := catalog.Localize([]string{"en"}, &httphandler.LocalizedError{Key: "unknown"})
+		require.EqualError(t, err, `no message for "unknown"`)
+	})
+
+	t.Run("fallback languages", func(t *testing.T) {
+		catalog.SetFallbackLanguages("de-AT", "ru")
+		message, language, err := catalog.Localize([]string{"de-AT"}, &httphandler.LocalizedError{Key: "items_left", Count: 5})
+		require.NoError(t, err)
+		require.Equal(t, "Осталось 5 товаров.", message)
+		require.Equal(t, "ru", language)
+	})
+
+	t.Run("custom plural rule", func(t *testing.T) {
+		catalog.SetPluralRule("en", func(n int) httphandler.PluralForm {
+			return httphandler.PluralOther
+		})
+		message, _, err := catalog.Localize([]string{"en"}, &httphandler.LocalizedError{Key: "items_left", Count: 1})
+		require.NoError(t, err)
+		require.Equal(t, "There are 1 items left.", message)
+	})
+
+	t.Run("invalid messages", func(t *testing.T) {
+		require.EqualError(t, catalog.AddMessage("", "key", httphandler.Message{Other: "message"}), "language cannot be empty")
+		require.EqualError(t, catalog.AddMessage("en", "key", httphandler.Message{One: "message"}), `message "key" has no other form`)
+		require.Error(t, catalog.AddMessage("en", "key", httphandler.Message{Other: "{{.Unclosed"}))
+		require.Error(t, catalog.LoadJSON("en", []byte("{")))
+		require.Error(t, catalog.LoadYAML("en", []byte("- a")))
+	})
+}
+
+func TestLocalizedError(t *testing.T) {
+	h := httphandler.New(nil)
+	h.SetCatalog(newTestCatalog(t))
+
+	publicError := &httphandler.LocalizedError{
+		Key:    "not_found",
+		Params: map[string]interface{}{"Resource": "Benutzer"},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		return &httphandler.HandlerError{
+			StatusCode:  http.StatusNotFound,
+			PublicError: publicError,
+		}
+	}))
+	mux.HandleFunc("/unknown", h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		return &httphandler.HandlerError{
+			StatusCode:  http.StatusNotFound,
+			PublicError: &httphandler.LocalizedError{Key: "unknown"},
+		}
+	}))
+
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	t.Run("accept language", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(s.URL),
+			hit.Send().Headers("Accept-Language").Add("fr-CH, de;q=0.9, en;q=0.8"),
+			hit.Expect().Status().Equal(http.StatusNotFound),
+			hit.Expect().Headers("Content-Language").Equal("de"),
+			hit.Expect().Headers("Vary").Equal("Accept-Language, Accept"),
+			hit.Expect().Body().JSON().JQ(".Error").Equal("Benutzer konnte nicht gefunden werden."),
+		)
+		// the error that was returned by the handler should not be modified
+		require.Empty(t, publicError.Message)
+	})
+
+	t.Run("excluded language", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(s.URL),
+			hit.Send().Headers("Accept-Language").Add("de;q=0, *"),
+			hit.Expect().Headers("Content-Language").Equal("en"),
+			hit.Expect().Body().JSON().JQ(".Error").Equal("The Benutzer could not be found."),
+		)
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(hit.JoinURL(s.URL, "unknown")),
+			hit.Expect().Status().Equal(http.StatusNotFound),
+			hit.Expect().Headers("Content-Language").Empty(),
+			hit.Expect().Body().JSON().JQ(".Error").Equal("unknown"),
+		)
+	})
+
+	t.Run("custom language func", func(t *testing.T) {
+		require.NoError(t, h.SetLanguageFunc(func(r *http.Request) []string {
+			return []string{"de"}
+		}))
+		hit.Test(t,
+			hit.Get(s.URL),
+			hit.Send().Headers("Accept-Language").Add("en"),
+			hit.Expect().Headers("Content-Language").Equal("de"),
+		)
+		require.EqualError(t, h.SetLanguageFunc(nil), "languageFunc cannot be nil")
+	})
+}
+
+func TestLocalizedErrorWithFormatSources(t *testing.T) {
+	h := httphandler.New(nil)
+	h.SetCatalog(newTestCatalog(t))
+	h.SetFormatSources(httphandler.FormatFromHeader("X-Format"))
+
+	s := httptest.NewServer(h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		return &httphandler.HandlerError{
+			StatusCode: http.StatusNotFound,
+			PublicError: &httphandler.LocalizedError{
+				Key:    "not_found",
+				Params: map[string]interface{}{"Resource": "Benutzer"},
+			},
+		}
+	}))
+	defer s.Close()
+
+	t.Run("accept header", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(s.URL),
+			hit.Send().Headers("Accept-Language").Add("de"),
+			hit.Expect().Status().Equal(http.StatusNotFound),
+			hit.Expect().Headers("Content-Language").Equal("de"),
+			hit.Expect().Headers("Vary").Equal("Accept-Language, X-Format, Accept"),
+			hit.Expect().Body().JSON().JQ(".Error").Equal("Benutzer konnte nicht gefunden werden."),
+		)
+	})
+
+	t.Run("format override", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(s.URL),
+			hit.Send().Headers("Accept-Language").Add("de"),
+			hit.Send().Headers("X-Format").Add("text"),
+			hit.Expect().Status().Equal(http.StatusNotFound),
+			hit.Expect().Headers("Content-Type").Equal("text/plain; charset=utf-8"),
+			hit.Expect().Headers("Vary").Equal("Accept-Language, X-Format"),
+		)
+	})
+}
@@ -0,0 +1,122 @@
+package httphandler
+
+import (
+	"math"
+	"net/http"
+)
+
+// CBORMarshaler is the interface implemented by a PublicError that can marshal itself into CBOR.
+// It is compatible with the Marshaler interface of github.com/fxamacker/cbor.
+type CBORMarshaler interface {
+	MarshalCBOR() ([]byte, error)
+}
+
+// DefaultCBOREncoder implements a CBOR (RFC 8949) encoder that renders the WireError as map with the same keys
+// DefaultJSONEncoder uses.
+// If the Error implements CBORMarshaler its output is used as value for the Error, otherwise the Error is marshaled
+// (using its JSON representation) and the Error() function is used if the marshaled error is empty.
+// The encoder is not part of the default Encoders.
+//
+// Example:
+//
+//	handler.SetEncoder("application/cbor", DefaultCBOREncoder())
+func DefaultCBOREncoder() EncodeFunc {
+	return func(w http.ResponseWriter, r *http.Request, e *WireError) error {
+		buf, err := encodeBinaryWireError(&cborWriter{}, e, func(err error) ([]byte, bool, error) {
+			m, ok := err.(CBORMarshaler)
+			if !ok {
+				return nil, false, nil
+			}
+			buf, err := m.MarshalCBOR()
+			return buf, true, err
+		})
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(buf)
+		return err
+	}
+}
+
+// CBOR major types.
+const (
+	cborUnsignedInt byte = 0 << 5
+	cborNegativeInt byte = 1 << 5
+	cborTextString  byte = 3 << 5
+	cborArray       byte = 4 << 5
+	cborMap         byte = 5 << 5
+)
+
+// cborWriter writes CBOR in the preferred serialization (the shortest form of every argument).
+type cborWriter struct {
+	buf []byte
+}
+
+func (c *cborWriter) writeNil() {
+	c.buf = append(c.buf, 0xf6)
+}
+
+func (c *cborWriter) writeBool(v bool) {
+	if v {
+		c.buf = append(c.buf, 0xf5)
+		return
+	}
+	c.buf = append(c.buf, 0xf4)
+}
+
+func (c *cborWriter) writeInt(v int64) {
+	if v >= 0 {
+		c.writeHead(cborUnsignedInt, uint64(v))
+		return
+	}
+	c.writeHead(cborNegativeInt, uint64(-1-v))
+}
+
+func (c *cborWriter) writeUint(v uint64) {
+	c.writeHead(cborUnsignedInt, v)
+}
+
+func (c *cborWriter) writeFloat(v float64) {
+	c.buf = append(c.buf, 0xfb)
+	c.buf = appendUint64(c.buf, math.Float64bits(v))
+}
+
+func (c *cborWriter) writeString(v string) {
+	c.writeHead(cborTextString, uint64(len(v)))
+	c.buf = append(c.buf, v...)
+}
+
+func (c *cborWriter) writeArrayHeader(n int) {
+	c.writeHead(cborArray, uint64(n))
+}
+
+func (c *cborWriter) writeMapHeader(n int) {
+	c.writeHead(cborMap, uint64(n))
+}
+
+// writeHead writes the initial byte of the major type and its argument.
+func (c *cborWriter) writeHead(major byte, v uint64) {
+	switch {
+	case v < 24: //nolint:gomnd // arguments below 24 are stored in the initial byte
+		c.buf = append(c.buf, major|byte(v))
+	case v <= math.MaxUint8:
+		c.buf = append(c.buf, major|24, byte(v))
+	case v <= math.MaxUint16:
+		c.buf = append(c.buf, major|25)
+		c.buf = appendUint16(c.buf, uint16(v))
+	case v <= math.MaxUint32:
+		c.buf = append(c.buf, major|26)
+		c.buf = appendUint32(c.buf, uint32(v))
+	default:
+		c.buf = append(c.buf, major|27)
+		c.buf = appendUint64(c.buf, v)
+	}
+}
+
+func (c *cborWriter) writeRaw(v []byte) {
+	c.buf = append(c.buf, v...)
+}
+
+func (c *cborWriter) bytes() []byte {
+	return c.buf
+}
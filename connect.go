@@ -0,0 +1,124 @@
+package httphandler
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// connectCodes are the names of the codes in the Connect protocol.
+var connectCodes = map[RPCCode]string{
+	RPCCodeCanceled:           "canceled",
+	RPCCodeUnknown:            "unknown",
+	RPCCodeInvalidArgument:    "invalid_argument",
+	RPCCodeDeadlineExceeded:   "deadline_exceeded",
+	RPCCodeNotFound:           "not_found",
+	RPCCodeAlreadyExists:      "already_exists",
+	RPCCodePermissionDenied:   "permission_denied",
+	RPCCodeResourceExhausted:  "resource_exhausted",
+	RPCCodeFailedPrecondition: "failed_precondition",
+	RPCCodeAborted:            "aborted",
+	RPCCodeOutOfRange:         "out_of_range",
+	RPCCodeUnimplemented:      "unimplemented",
+	RPCCodeInternal:           "internal",
+	RPCCodeUnavailable:        "unavailable",
+	RPCCodeDataLoss:           "data_loss",
+	RPCCodeUnauthenticated:    "unauthenticated",
+}
+
+// ConnectHTTPStatus returns the http status code the Connect protocol mandates for the code.
+func ConnectHTTPStatus(code RPCCode) int {
+	switch code {
+	case RPCCodeCanceled:
+		return statusClientClosedRequest
+	case RPCCodeInvalidArgument, RPCCodeFailedPrecondition, RPCCodeOutOfRange:
+		return http.StatusBadRequest
+	case RPCCodeDeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case RPCCodeNotFound:
+		return http.StatusNotFound
+	case RPCCodeAlreadyExists, RPCCodeAborted:
+		return http.StatusConflict
+	case RPCCodePermissionDenied:
+		return http.StatusForbidden
+	case RPCCodeResourceExhausted:
+		return http.StatusTooManyRequests
+	case RPCCodeUnimplemented:
+		return http.StatusNotImplemented
+	case RPCCodeUnavailable:
+		return http.StatusServiceUnavailable
+	case RPCCodeUnauthenticated:
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// NewConnectError returns a HandlerError with the status code the Connect protocol mandates for the code and a
+// RPCStatus as PublicError.
+//
+// Example:
+//
+//	return NewConnectError(RPCCodeNotFound, "user not found")
+func NewConnectError(code RPCCode, message string, details ...RPCStatusDetail) *HandlerError {
+	return &HandlerError{
+		StatusCode: ConnectHTTPStatus(code),
+		PublicError: &RPCStatus{
+			Code:    code,
+			Message: message,
+			Details: details,
+		},
+	}
+}
+
+// ConnectErrorEncoder implements an encoder for the error JSON of the Connect protocol
+// (e.g. {"code":"not_found","message":"user not found","details":[...]}).
+// It should be registered for "application/json" (and as fallback encoder) if the handlers serve Connect clients.
+// The status is created the same way RPCStatusProtoEncoder does, use NewConnectError to send the status code that
+// fits the code. Details are encoded with their type name and the base64 encoded protobuf message.
+//
+// Example:
+//
+//	handler.SetEncoder("application/json", ConnectErrorEncoder())
+//	handler.SetFallbackEncoder("application/json", ConnectErrorEncoder())
+func ConnectErrorEncoder() EncodeFunc {
+	return func(w http.ResponseWriter, r *http.Request, e *WireError) error {
+		status := rpcStatus(e)
+
+		code, ok := connectCodes[status.Code]
+		if !ok {
+			code = connectCodes[RPCCodeUnknown]
+		}
+
+		type connectDetail struct {
+			Type  string `json:"type"`
+			Value string `json:"value"`
+		}
+		details := make([]connectDetail, 0, len(status.Details))
+		for _, detail := range status.Details {
+			value, err := detail.MarshalProto()
+			if err != nil {
+				return errors.Wrapf(err, "unable to encode detail %q", detail.TypeURL())
+			}
+			typeURL := detail.TypeURL()
+			details = append(details, connectDetail{
+				// the type is the fully qualified message name, without the prefix of the type url
+				Type:  typeURL[strings.LastIndexByte(typeURL, '/')+1:],
+				Value: base64.RawStdEncoding.EncodeToString(value),
+			})
+		}
+
+		return json.NewEncoder(w).Encode(struct {
+			Code    string          `json:"code"`
+			Message string          `json:"message,omitempty"`
+			Details []connectDetail `json:"details,omitempty"`
+		}{
+			Code:    code,
+			Message: status.Message,
+			Details: details,
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package httphandler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Eun/go-hit"
+
+	"github.com/pkg/errors"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/talon-one/go-httphandler"
+)
+
+func TestConnectHTTPStatus(t *testing.T) {
+	require.Equal(t, 499, httphandler.ConnectHTTPStatus(httphandler.RPCCodeCanceled))
+	require.Equal(t, http.StatusBadRequest, httphandler.ConnectHTTPStatus(httphandler.RPCCodeFailedPrecondition))
+	require.Equal(t, http.StatusConflict, httphandler.ConnectHTTPStatus(httphandler.RPCCodeAborted))
+	require.Equal(t, http.StatusTooManyRequests, httphandler.ConnectHTTPStatus(httphandler.RPCCodeResourceExhausted))
+	require.Equal(t, http.StatusInternalServerError, httphandler.ConnectHTTPStatus(httphandler.RPCCodeDataLoss))
+}
+
+func TestConnectErrorEncoder(t *testing.T) {
+	h := httphandler.New(nil)
+	require.NoError(t, h.SetRequestUUIDFunc(func() string {
+		return "0123456789"
+	}))
+	require.NoError(t, h.SetEncoder("application/json", httphandler.ConnectErrorEncoder()))
+	require.NoError(t, h.SetFallbackEncoder("application/json", httphandler.ConnectErrorEncoder()))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/connect", h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		return httphandler.NewConnectError(httphandler.RPCCodeNotFound, "user not found", &httphandler.RPCErrorInfo{Reason: "R"})
+	}))
+	mux.HandleFunc("/error", h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		return &httphandler.HandlerError{
+			StatusCode:  http.StatusBadRequest,
+			PublicError: errors.New("invalid user"),
+		}
+	}))
+
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	t.Run("connect error", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(hit.JoinURL(s.URL, "connect")),
+			hit.Expect().Status().Equal(http.StatusNotFound),
+			hit.Expect().Headers("Content-Type").Equal("application/json"),
+			hit.Expect().Body().JSON().Equal(map[string]interface{}{
+				"code":    "not_found",
+				"message": "user not found",
+				"details": []interface{}{
+					map[string]interface{}{"type": "google.rpc.ErrorInfo", "value": "CgFS"},
+					map[string]interface{}{"type": "google.rpc.RequestInfo", "value": "CgowMTIzNDU2Nzg5"},
+				},
+			}),
+		)
+	})
+
+	t.Run("plain error", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(hit.JoinURL(s.URL, "error")),
+			hit.Expect().Status().Equal(http.StatusBadRequest),
+			hit.Expect().Body().JSON().JQ(".code").Equal("invalid_argument"),
+			hit.Expect().Body().JSON().JQ(".message").Equal("invalid user"),
+		)
+	})
+}
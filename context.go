@@ -1,6 +1,9 @@
 package httphandler
 
-import "net/http"
+import (
+	"context"
+	"net/http"
+)
 
 type contextKey int
 
@@ -10,7 +13,13 @@ const (
 
 // GetRequestUUID returns the request uuid for the specified request.
 func GetRequestUUID(r *http.Request) string {
-	if rv := r.Context().Value(uuidKey); rv != nil {
+	return GetRequestUUIDFromContext(r.Context())
+}
+
+// GetRequestUUIDFromContext returns the request uuid that is stored in the context of the request, it can be used in
+// handlers that only get the context (see TypedHandleFunc).
+func GetRequestUUIDFromContext(ctx context.Context) string {
+	if rv := ctx.Value(uuidKey); rv != nil {
 		return rv.(string)
 	}
 	// should not be possible
@@ -0,0 +1,430 @@
+package httphandler
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// DecodeFunc is the decode function that will be called to decode the request body into v.
+// Decoders should return a *DecodeError if the body is malformed, so the client receives the position of the error.
+type DecodeFunc func(r *http.Request, v interface{}) error
+
+// DefaultMaxBodySize is the maximum size of request bodies that are decoded by Decode, if Options.MaxBodySize is 0.
+const DefaultMaxBodySize = 10 << 20
+
+// DecodeError is the PublicError of the 400 Bad Request HandlerError that Decode returns if the request body is
+// malformed (or does not fit the target value).
+type DecodeError struct {
+	// Offset is the byte offset in the request body at which the error occurred, it is -1 if it is unknown.
+	Offset int64
+	// Msg describes the error.
+	Msg string
+}
+
+func (e *DecodeError) Error() string {
+	if e.Offset < 0 {
+		return "invalid request body: " + e.Msg
+	}
+	return fmt.Sprintf("invalid request body at byte offset %d: %s", e.Offset, e.Msg)
+}
+
+// UnsupportedMediaTypeError is the PublicError of the 415 Unsupported Media Type HandlerError that Decode returns if
+// there is no decoder for the Content-Type of the request.
+type UnsupportedMediaTypeError struct {
+	// ContentType is the media type of the request, it is empty if the request had no Content-Type.
+	ContentType string
+	// SupportedMediaTypes are the media types that can be decoded.
+	SupportedMediaTypes []string
+}
+
+func (e *UnsupportedMediaTypeError) Error() string {
+	if e.ContentType == "" {
+		return fmt.Sprintf("missing content type, supported media types are: %s",
+			strings.Join(e.SupportedMediaTypes, ", "))
+	}
+	return fmt.Sprintf("unsupported content type %q, supported media types are: %s",
+		e.ContentType, strings.Join(e.SupportedMediaTypes, ", "))
+}
+
+// errBodyTooLarge is returned by the body reader of Decode if the body exceeds the maximum size.
+var errBodyTooLarge = errors.New("request body too large")
+
+// maxBytesReader reads up to n bytes from the ReadCloser, reading beyond results in errBodyTooLarge.
+type maxBytesReader struct {
+	rc io.ReadCloser
+	n  int64
+}
+
+func (r *maxBytesReader) Read(p []byte) (int, error) {
+	if r.n < 0 {
+		return 0, errBodyTooLarge
+	}
+	// read one byte more than allowed to detect bodies that are too large
+	if int64(len(p)) > r.n+1 {
+		p = p[:r.n+1]
+	}
+	n, err := r.rc.Read(p)
+	r.n -= int64(n)
+	if r.n < 0 {
+		return n, errBodyTooLarge
+	}
+	return n, err
+}
+
+func (r *maxBytesReader) Close() error {
+	return r.rc.Close()
+}
+
+// bodyTooLarge reports whether err was caused by a request body that is too large and returns the limit that has been
+// exceeded. Besides the limit of Decode (maxBodySize) the body might have been limited with http.MaxBytesReader.
+func bodyTooLarge(err error, maxBodySize int64) (limit int64, ok bool) {
+	var maxBytesError *http.MaxBytesError
+	switch {
+	case errors.Is(err, errBodyTooLarge):
+		return maxBodySize, true
+	case errors.As(err, &maxBytesError):
+		return maxBytesError.Limit, true
+	default:
+		return 0, false
+	}
+}
+
+// Decode decodes the request body into v using the decoder of the Options.Decoders that is registered for the
+// Content-Type of the request. Media types with a structured syntax suffix (e.g. "application/vnd.api+json") that have
+// no decoder on their own use the decoder of the suffixes base media type (e.g. "application/json").
+// The returned HandlerError can be returned from the handler func as it is:
+//   - 415 Unsupported Media Type with an *UnsupportedMediaTypeError if there is no decoder for the Content-Type,
+//   - 413 Request Entity Too Large if the body exceeds the Options.MaxBodySize (or the limit of an http.MaxBytesReader),
+//   - 400 Bad Request with a *DecodeError if the body is malformed.
+//
+// Example:
+//
+//	http.HandleFunc("/user", handler.HandleFunc(func(w http.ResponseWriter, r *http.Request) *HandlerError {
+//	    var user User
+//	    if err := handler.Decode(r, &user); err != nil {
+//	        return err
+//	    }
+//	    ...
+//	}))
+func (h *Handler) Decode(r *http.Request, v interface{}) *HandlerError {
+	contentType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil && r.Header.Get("Content-Type") != "" {
+		contentType = strings.TrimSpace(r.Header.Get("Content-Type"))
+	}
+	contentType = strings.ToLower(contentType)
+	f := lookupDecoder(h.options.Decoders, contentType)
+	if f == nil {
+		return &HandlerError{
+			StatusCode: http.StatusUnsupportedMediaType,
+			PublicError: &UnsupportedMediaTypeError{
+				ContentType:         contentType,
+				SupportedMediaTypes: decoderContentTypes(h.options.Decoders),
+			},
+			InternalError: errors.Errorf("no decoder for content type %q", contentType),
+		}
+	}
+
+	if r.Body == nil {
+		r.Body = http.NoBody
+	}
+	if _, limited := r.Body.(*maxBytesReader); !limited && h.options.maxBodySize() >= 0 {
+		// the body is only wrapped once, even if it is decoded multiple times
+		r.Body = &maxBytesReader{rc: r.Body, n: h.options.maxBodySize()}
+	}
+
+	err = f(r, v)
+	if err == nil {
+		return nil
+	}
+	if limit, ok := bodyTooLarge(err, h.options.maxBodySize()); ok {
+		return &HandlerError{
+			StatusCode:    http.StatusRequestEntityTooLarge,
+			PublicError:   errors.Errorf("request body exceeds the limit of %d bytes", limit),
+			InternalError: errors.Wrapf(err, "unable to decode %q", contentType),
+		}
+	}
+	var decodeError *DecodeError
+	if !errors.As(err, &decodeError) {
+		// the error might contain Go types or other internals, it is only logged
+		decodeError = &DecodeError{Offset: -1, Msg: "malformed request body"}
+	}
+	return &HandlerError{
+		StatusCode:    http.StatusBadRequest,
+		PublicError:   decodeError,
+		InternalError: errors.Wrapf(err, "unable to decode %q", contentType),
+	}
+}
+
+// Decode decodes the request body into v using the DefaultHandler.
+// See also Handler.Decode.
+func Decode(r *http.Request, v interface{}) *HandlerError {
+	return DefaultHandler.Decode(r, v)
+}
+
+// maxBodySize returns the maximum size of request bodies, a negative size disables the limit.
+func (o *Options) maxBodySize() int64 {
+	if o.MaxBodySize == 0 {
+		return DefaultMaxBodySize
+	}
+	return o.MaxBodySize
+}
+
+// lookupDecoder returns the decoder for the specified content type, see lookupEncoder.
+func lookupDecoder(decoders map[string]DecodeFunc, contentType string) DecodeFunc {
+	if contentType == "" {
+		return nil
+	}
+	if f, ok := decoders[contentType]; ok {
+		return f
+	}
+	if base, ok := structuredSyntaxSuffixes[structuredSyntaxSuffix(contentType)]; ok {
+		return decoders[base]
+	}
+	return nil
+}
+
+// decoderContentTypes returns the content types of the decoders in a stable order.
+func decoderContentTypes(decoders map[string]DecodeFunc) []string {
+	contentTypes := make([]string, 0, len(decoders))
+	for contentType := range decoders {
+		contentTypes = append(contentTypes, strings.ToLower(contentType))
+	}
+	sort.Strings(contentTypes)
+	return contentTypes
+}
+
+func defaultDecoders() map[string]DecodeFunc {
+	decoders := map[string]DecodeFunc{
+		"application/json":                  DefaultJSONDecoder(),
+		"application/xml":                   DefaultXMLDecoder(),
+		"text/xml":                          DefaultXMLDecoder(),
+		"application/x-www-form-urlencoded": DefaultFormDecoder(),
+	}
+	for _, contentType := range YAMLContentTypes {
+		decoders[contentType] = DefaultYAMLDecoder()
+	}
+	return decoders
+}
+
+// DefaultJSONDecoder implements the default JSON decoder, it decodes the body using encoding/json.
+// Syntax errors and values that do not fit v result in a *DecodeError with the offset of the error.
+func DefaultJSONDecoder() DecodeFunc {
+	return func(r *http.Request, v interface{}) error {
+		err := json.NewDecoder(r.Body).Decode(v)
+		var syntaxError *json.SyntaxError
+		var typeError *json.UnmarshalTypeError
+		switch {
+		case err == nil:
+			return nil
+		case errors.As(err, &syntaxError):
+			return &DecodeError{Offset: syntaxError.Offset, Msg: syntaxError.Error()}
+		case errors.As(err, &typeError):
+			return &DecodeError{Offset: typeError.Offset, Msg: jsonTypeErrorMessage(typeError)}
+		case errors.Is(err, io.EOF):
+			return &DecodeError{Offset: 0, Msg: "request body is empty"}
+		case errors.Is(err, io.ErrUnexpectedEOF):
+			return &DecodeError{Offset: -1, Msg: "unexpected end of request body"}
+		default:
+			return err
+		}
+	}
+}
+
+// jsonTypeErrorMessage describes the UnmarshalTypeError with the path of the field and the JSON types, so the Go types
+// of the target value are not exposed (e.g. `invalid value for "address.zip": expected string, got number`).
+func jsonTypeErrorMessage(typeError *json.UnmarshalTypeError) string {
+	msg := "invalid value"
+	if typeError.Field != "" {
+		msg += fmt.Sprintf(" for %q", typeError.Field)
+	}
+	if typeError.Type != nil {
+		msg += ": expected " + jsonType(typeError.Type)
+	}
+	if typeError.Value != "" {
+		msg += ", got " + typeError.Value
+	}
+	return msg
+}
+
+// textUnmarshalerType is the type of encoding.TextUnmarshaler, encoding/json expects strings for such values.
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// jsonType returns the JSON type that encoding/json expects for values of type t.
+func jsonType(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return "string"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// byte slices are base64 encoded strings
+			return "string"
+		}
+		return "array"
+	case reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	default:
+		return "value"
+	}
+}
+
+// DefaultXMLDecoder implements the default XML decoder, it decodes the body using encoding/xml.
+// Malformed documents and values that do not fit v result in a *DecodeError with the offset the decoder reached.
+func DefaultXMLDecoder() DecodeFunc {
+	return func(r *http.Request, v interface{}) error {
+		dec := xml.NewDecoder(r.Body)
+		err := dec.Decode(v)
+		var syntaxError *xml.SyntaxError
+		var unmarshalError xml.UnmarshalError
+		var numError *strconv.NumError
+		var maxBytesError *http.MaxBytesError
+		switch {
+		case err == nil:
+			return nil
+		case errors.Is(err, errBodyTooLarge), errors.As(err, &maxBytesError):
+			return err
+		case errors.As(err, &syntaxError):
+			return &DecodeError{Offset: dec.InputOffset(), Msg: syntaxError.Error()}
+		case errors.As(err, &unmarshalError):
+			// describes unexpected elements (e.g. "expected element type <user> but have <users>")
+			return &DecodeError{Offset: dec.InputOffset(), Msg: string(unmarshalError)}
+		case errors.As(err, &numError):
+			return &DecodeError{Offset: dec.InputOffset(), Msg: numErrorMessage(numError)}
+		case errors.Is(err, io.EOF):
+			return &DecodeError{Offset: 0, Msg: "request body is empty"}
+		default:
+			return err
+		}
+	}
+}
+
+// numErrorMessage describes the strconv.NumError without the name of the parse function, so the message matches the
+// messages of the JSON decoder (e.g. "invalid value: expected integer").
+func numErrorMessage(numError *strconv.NumError) string {
+	if errors.Is(numError.Err, strconv.ErrRange) {
+		return "invalid value: out of range"
+	}
+	switch numError.Func {
+	case "ParseBool":
+		return "invalid value: expected boolean"
+	case "ParseInt", "ParseUint":
+		return "invalid value: expected integer"
+	default:
+		return "invalid value: expected number"
+	}
+}
+
+// yamlLinePattern matches the line number in errors of the YAML decoder.
+var yamlLinePattern = regexp.MustCompile(`line (\d+)`)
+
+// yamlTypeErrorPattern matches the tag of the YAML value and the Go type of the target in errors of the YAML decoder
+// (e.g. "line 2: cannot unmarshal !!str `old` into int").
+var yamlTypeErrorPattern = regexp.MustCompile(`cannot unmarshal !!(\w+).* into (\S+)$`)
+
+// yamlTagTypes are the names of the YAML tags, they match the JSON types in the messages of the JSON decoder.
+var yamlTagTypes = map[string]string{
+	"bool":  "boolean",
+	"int":   "integer",
+	"float": "number",
+	"str":   "string",
+	"null":  "null",
+	"seq":   "array",
+	"map":   "object",
+}
+
+// yamlGoTypes are the names of the builtin Go types in errors of the YAML decoder, other types are not exposed.
+var yamlGoTypes = map[string]string{
+	"bool": "boolean", "string": "string",
+	"int": "integer", "int8": "integer", "int16": "integer", "int32": "integer", "int64": "integer",
+	"uint": "integer", "uint8": "integer", "uint16": "integer", "uint32": "integer", "uint64": "integer",
+	"float32": "number", "float64": "number",
+}
+
+// DefaultYAMLDecoder implements the default YAML decoder, it decodes the body using gopkg.in/yaml.v3.
+// Malformed documents result in a *DecodeError with the offset of the line the error occurred in.
+func DefaultYAMLDecoder() DecodeFunc {
+	return func(r *http.Request, v interface{}) error {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+		if len(bytes.TrimSpace(body)) == 0 {
+			return &DecodeError{Offset: 0, Msg: "request body is empty"}
+		}
+		if err := yaml.Unmarshal(body, v); err != nil {
+			return &DecodeError{Offset: yamlErrorOffset(body, err), Msg: yamlErrorMessage(err)}
+		}
+		return nil
+	}
+}
+
+// yamlErrorMessage describes the YAML error without the prefix of the YAML package and the Go types of the target value
+// (e.g. "invalid value: expected integer, got string").
+func yamlErrorMessage(err error) string {
+	var typeError *yaml.TypeError
+	if !errors.As(err, &typeError) || len(typeError.Errors) == 0 {
+		return strings.TrimPrefix(err.Error(), "yaml: ")
+	}
+	msg := "invalid value"
+	match := yamlTypeErrorPattern.FindStringSubmatch(typeError.Errors[0])
+	if match == nil {
+		return msg
+	}
+	if expected, ok := yamlGoTypes[match[2]]; ok {
+		msg += ": expected " + expected
+		if got, ok := yamlTagTypes[match[1]]; ok {
+			msg += ", got " + got
+		}
+	}
+	return msg
+}
+
+// yamlErrorOffset returns the offset of the line that is referenced in the YAML error, or -1.
+func yamlErrorOffset(body []byte, err error) int64 {
+	match := yamlLinePattern.FindStringSubmatch(err.Error())
+	if match == nil {
+		return -1
+	}
+	line, convErr := strconv.Atoi(match[1])
+	if convErr != nil || line < 1 {
+		return -1
+	}
+	offset := 0
+	for ; line > 1; line-- {
+		i := bytes.IndexByte(body[offset:], '\n')
+		if i < 0 {
+			return -1
+		}
+		offset += i + 1
+	}
+	return int64(offset)
+}
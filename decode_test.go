@@ -0,0 +1,223 @@
+package httphandler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Eun/go-hit"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/talon-one/go-httphandler"
+)
+
+type decodeUser struct {
+	Name string `json:"name" xml:"Name" yaml:"name"`
+	Age  int    `json:"age" xml:"Age" yaml:"age"`
+}
+
+func TestDecode(t *testing.T) {
+	h := httphandler.New(nil)
+	require.NoError(t, h.SetRequestUUIDFunc(func() string {
+		return "0123456789"
+	}))
+	h.SetMaxBodySize(64)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/user", h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		var user decodeUser
+		if err := h.Decode(r, &user); err != nil {
+			return err
+		}
+		return h.Respond(w, r, http.StatusOK, &user)
+	}))
+	mux.HandleFunc("/limited", h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		r.Body = http.MaxBytesReader(w, r.Body, 16)
+		var user decodeUser
+		if err := h.Decode(r, &user); err != nil {
+			return err
+		}
+		return h.Respond(w, r, http.StatusOK, &user)
+	}))
+
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	joe := map[string]interface{}{"name": "Joe", "age": 42}
+
+	t.Run("json", func(t *testing.T) {
+		hit.Test(t,
+			hit.Post(hit.JoinURL(s.URL, "user")),
+			hit.Send().Headers("Content-Type").Add("application/json; charset=utf-8"),
+			hit.Send().Body().String(`{"name":"Joe","age":42}`),
+			hit.Expect().Status().Equal(http.StatusOK),
+			hit.Expect().Body().JSON().Equal(joe),
+		)
+	})
+
+	t.Run("structured syntax suffix", func(t *testing.T) {
+		hit.Test(t,
+			hit.Post(hit.JoinURL(s.URL, "user")),
+			hit.Send().Headers("Content-Type").Add("application/vnd.users+json"),
+			hit.Send().Body().String(`{"name":"Joe","age":42}`),
+			hit.Expect().Status().Equal(http.StatusOK),
+			hit.Expect().Body().JSON().Equal(joe),
+		)
+	})
+
+	t.Run("xml", func(t *testing.T) {
+		hit.Test(t,
+			hit.Post(hit.JoinURL(s.URL, "user")),
+			hit.Send().Headers("Content-Type").Add("application/xml"),
+			hit.Send().Body().String(`<User><Name>Joe</Name><Age>42</Age></User>`),
+			hit.Expect().Status().Equal(http.StatusOK),
+			hit.Expect().Body().JSON().Equal(joe),
+		)
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+		hit.Test(t,
+			hit.Post(hit.JoinURL(s.URL, "user")),
+			hit.Send().Headers("Content-Type").Add("application/yaml"),
+			hit.Send().Body().String("name: Joe\nage: 42\n"),
+			hit.Expect().Status().Equal(http.StatusOK),
+			hit.Expect().Body().JSON().Equal(joe),
+		)
+	})
+
+	t.Run("unsupported media type", func(t *testing.T) {
+		hit.Test(t,
+			hit.Post(hit.JoinURL(s.URL, "user")),
+			hit.Send().Headers("Content-Type").Add("image/png"),
+			hit.Send().Body().String("..."),
+			hit.Expect().Status().Equal(http.StatusUnsupportedMediaType),
+			hit.Expect().Body().JSON().JQ(".Error.ContentType").Equal("image/png"),
+			hit.Expect().Body().JSON().JQ(".Error.SupportedMediaTypes[0]").Equal("application/json"),
+		)
+	})
+
+	t.Run("missing content type", func(t *testing.T) {
+		hit.Test(t,
+			hit.Post(hit.JoinURL(s.URL, "user")),
+			hit.Send().Body().String(`{"name":"Joe","age":42}`),
+			hit.Expect().Status().Equal(http.StatusUnsupportedMediaType),
+			hit.Expect().Body().JSON().JQ(".Error.ContentType").Equal(""),
+		)
+	})
+
+	t.Run("json syntax error", func(t *testing.T) {
+		hit.Test(t,
+			hit.Post(hit.JoinURL(s.URL, "user")),
+			hit.Send().Headers("Content-Type").Add("application/json"),
+			hit.Send().Headers("Accept").Add("text/plain"),
+			hit.Send().Body().String(`{"name":"Joe",}`),
+			hit.Expect().Status().Equal(http.StatusBadRequest),
+			hit.Expect().Body().String().Contains("invalid request body at byte offset 15"),
+		)
+	})
+
+	t.Run("json type error", func(t *testing.T) {
+		hit.Test(t,
+			hit.Post(hit.JoinURL(s.URL, "user")),
+			hit.Send().Headers("Content-Type").Add("application/json"),
+			hit.Send().Body().String(`{"name":"Joe","age":"old"}`),
+			hit.Expect().Status().Equal(http.StatusBadRequest),
+			hit.Expect().Body().JSON().JQ(".Error.Offset").Equal(25),
+			hit.Expect().Body().JSON().JQ(".Error.Msg").Equal(`invalid value for "age": expected integer, got string`),
+		)
+	})
+
+	t.Run("empty body", func(t *testing.T) {
+		hit.Test(t,
+			hit.Post(hit.JoinURL(s.URL, "user")),
+			hit.Send().Headers("Content-Type").Add("application/json"),
+			hit.Expect().Status().Equal(http.StatusBadRequest),
+			hit.Expect().Body().JSON().JQ(".Error.Msg").Equal("request body is empty"),
+		)
+	})
+
+	t.Run("yaml type error", func(t *testing.T) {
+		hit.Test(t,
+			hit.Post(hit.JoinURL(s.URL, "user")),
+			hit.Send().Headers("Content-Type").Add("application/yaml"),
+			hit.Send().Body().String("name: Joe\nage: old\n"),
+			hit.Expect().Status().Equal(http.StatusBadRequest),
+			hit.Expect().Body().JSON().JQ(".Error.Offset").Equal(10),
+			hit.Expect().Body().JSON().JQ(".Error.Msg").Equal("invalid value: expected integer, got string"),
+		)
+	})
+
+	t.Run("xml type error", func(t *testing.T) {
+		hit.Test(t,
+			hit.Post(hit.JoinURL(s.URL, "user")),
+			hit.Send().Headers("Content-Type").Add("application/xml"),
+			hit.Send().Body().String(`<User><Name>Joe</Name><Age>old</Age></User>`),
+			hit.Expect().Status().Equal(http.StatusBadRequest),
+			hit.Expect().Body().JSON().JQ(".Error.Msg").Equal("invalid value: expected integer"),
+		)
+	})
+
+	t.Run("body too large", func(t *testing.T) {
+		hit.Test(t,
+			hit.Post(hit.JoinURL(s.URL, "user")),
+			hit.Send().Headers("Content-Type").Add("application/json"),
+			hit.Send().Headers("Accept").Add("text/plain"),
+			hit.Send().Body().String(`{"name":"`+strings.Repeat("a", 64)+`"}`),
+			hit.Expect().Status().Equal(http.StatusRequestEntityTooLarge),
+			hit.Expect().Body().String().Contains("request body exceeds the limit of 64 bytes"),
+		)
+	})
+
+	t.Run("http.MaxBytesReader", func(t *testing.T) {
+		hit.Test(t,
+			hit.Post(hit.JoinURL(s.URL, "limited")),
+			hit.Send().Headers("Content-Type").Add("application/json"),
+			hit.Send().Headers("Accept").Add("text/plain"),
+			hit.Send().Body().String(`{"name":"Joe","age":42}`),
+			hit.Expect().Status().Equal(http.StatusRequestEntityTooLarge),
+			hit.Expect().Body().String().Contains("request body exceeds the limit of 16 bytes"),
+		)
+		hit.Test(t,
+			hit.Post(hit.JoinURL(s.URL, "limited")),
+			hit.Send().Headers("Content-Type").Add("application/xml"),
+			hit.Send().Headers("Accept").Add("text/plain"),
+			hit.Send().Body().String(`<User><Name>Joe</Name><Age>42</Age></User>`),
+			hit.Expect().Status().Equal(http.StatusRequestEntityTooLarge),
+			hit.Expect().Body().String().Contains("request body exceeds the limit of 16 bytes"),
+		)
+	})
+}
+
+func TestSetDecodersOption(t *testing.T) {
+	h := httphandler.New(nil)
+	require.NoError(t, h.SetDecoders(map[string]httphandler.DecodeFunc{
+		"Text/CSV": func(r *http.Request, v interface{}) error {
+			v.(*decodeUser).Name = "csv"
+			return nil
+		},
+	}))
+	s := httptest.NewServer(h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		var user decodeUser
+		if err := h.Decode(r, &user); err != nil {
+			return err
+		}
+		return h.Respond(w, r, http.StatusOK, &user)
+	}))
+	defer s.Close()
+
+	hit.Test(t,
+		hit.Post(s.URL),
+		hit.Send().Headers("Content-Type").Add("text/csv"),
+		hit.Expect().Status().Equal(http.StatusOK),
+		hit.Expect().Body().JSON().JQ(".name").Equal("csv"),
+	)
+	// the default decoders are kept
+	hit.Test(t,
+		hit.Post(s.URL),
+		hit.Send().Headers("Content-Type").Add("application/json"),
+		hit.Send().Body().String(`{"name":"Joe","age":42}`),
+		hit.Expect().Status().Equal(http.StatusOK),
+		hit.Expect().Body().JSON().JQ(".name").Equal("Joe"),
+	)
+}
@@ -0,0 +1,410 @@
+package httphandler
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Encoder encodes the WireError into one or more media types, it carries the metadata an EncodeFunc lacks.
+// Encoders are registered with the EncoderRegistry of a Handler (see Handler.Encoders).
+type Encoder interface {
+	// MediaTypes returns the media types the encoder produces (e.g. "application/json").
+	MediaTypes() []string
+	// Charset returns the charset of the output (e.g. "utf-8"), it is added to the Content-Type header.
+	// It should be empty for binary formats.
+	Charset() string
+	// Weight returns the preference of the server: if the client accepts several media types equally the encoder with
+	// the highest weight is used.
+	Weight() float64
+	// Encode encodes the WireError.
+	Encode(w http.ResponseWriter, r *http.Request, e *WireError) error
+}
+
+// FuncEncoder is an Encoder that wraps an EncodeFunc, use WrapEncodeFunc to create one.
+type FuncEncoder struct {
+	encode     EncodeFunc
+	mediaTypes []string
+	charset    string
+	weight     float64
+}
+
+// WrapEncodeFunc wraps the EncodeFunc into an Encoder for the specified media types.
+//
+// Example:
+//
+//	handler.Encoders().Register(WrapEncodeFunc(DefaultYAMLEncoder(), "application/yaml").WithCharset("utf-8"))
+func WrapEncodeFunc(f EncodeFunc, mediaTypes ...string) *FuncEncoder {
+	return &FuncEncoder{
+		encode:     f,
+		mediaTypes: mediaTypes,
+	}
+}
+
+// WithCharset sets the charset of the encoder.
+func (e *FuncEncoder) WithCharset(charset string) *FuncEncoder {
+	e.charset = charset
+	return e
+}
+
+// WithWeight sets the weight of the encoder.
+func (e *FuncEncoder) WithWeight(weight float64) *FuncEncoder {
+	e.weight = weight
+	return e
+}
+
+// MediaTypes returns the media types of the encoder.
+func (e *FuncEncoder) MediaTypes() []string {
+	return e.mediaTypes
+}
+
+// Charset returns the charset of the encoder.
+func (e *FuncEncoder) Charset() string {
+	return e.charset
+}
+
+// Weight returns the weight of the encoder.
+func (e *FuncEncoder) Weight() float64 {
+	return e.weight
+}
+
+// Encode calls the wrapped EncodeFunc.
+func (e *FuncEncoder) Encode(w http.ResponseWriter, r *http.Request, we *WireError) error {
+	return e.encode(w, r, we)
+}
+
+// EncoderInfo describes an encoder of the EncoderRegistry, see EncoderRegistry.List.
+type EncoderInfo struct {
+	// MediaTypes are the media types of the encoder, followed by its aliases.
+	MediaTypes []string
+	// Charset is the charset of the encoder.
+	Charset string
+	// Weight is the weight of the encoder.
+	Weight float64
+	// Registered reports whether the encoder was registered with the EncoderRegistry, encoders that are only
+	// present in the Encoders of the Options are not registered.
+	Registered bool
+}
+
+// encoderEntry is an Encoder that was registered with the EncoderRegistry.
+type encoderEntry struct {
+	encoder Encoder
+	// mediaTypes are the media types and aliases the encoder is registered for.
+	mediaTypes []string
+}
+
+// EncoderRegistry is an ordered registry of Encoders.
+// The registry keeps the Encoders of the Options in sync, so encoders that are set with SetEncoder (or directly in
+// the Encoders map) can still be used. Registering an encoder states a preference of the server: if the client
+// accepts several media types equally (e.g. "*/*") the encoder with the highest Weight is used, on equal weights
+// registered encoders are preferred in the order of the registry (see Prefer) over encoders that are only present in
+// the Encoders of the Options (of which the fallback encoder is preferred).
+// The registry is meant to be set up before the Handler serves requests, it must not be changed concurrently to
+// requests (like the Options).
+type EncoderRegistry struct {
+	options *Options
+	entries []*encoderEntry
+	// mediaTypes caches the media types in the order of preference, so negotiating does not have to rank the encoders
+	// on every request. It is rebuilt whenever the registry, the Encoders or the fallback encoder are changed using
+	// their setters.
+	mediaTypes []string
+}
+
+// newEncoderRegistry returns a new EncoderRegistry for the Options.
+func newEncoderRegistry(options *Options) *EncoderRegistry {
+	r := &EncoderRegistry{options: options}
+	r.update()
+	return r
+}
+
+// clone returns a copy of the registry for the Options.
+func (r *EncoderRegistry) clone(options *Options) *EncoderRegistry {
+	c := &EncoderRegistry{options: options, entries: make([]*encoderEntry, 0, len(r.entries))}
+	for _, entry := range r.entries {
+		c.entries = append(c.entries, &encoderEntry{
+			encoder:    entry.encoder,
+			mediaTypes: append([]string(nil), entry.mediaTypes...),
+		})
+	}
+	c.update()
+	return c
+}
+
+// encoderRegistry returns the EncoderRegistry of the Options.
+// New creates the registry, so it is only created here for Options that are set up before they are passed to New.
+func (o *Options) encoderRegistry() *EncoderRegistry {
+	if o.registry == nil {
+		o.registry = newEncoderRegistry(o)
+	}
+	return o.registry
+}
+
+// Register registers the encoder for its media types, previous encoders for these media types are replaced.
+// If the encoder implements ValueEncoder it is also used by Respond for these media types, otherwise the value encoders
+// for these media types are kept.
+func (r *EncoderRegistry) Register(encoder Encoder) error {
+	if encoder == nil {
+		return errors.New("encoder cannot be nil")
+	}
+	if len(encoder.MediaTypes()) == 0 {
+		return errors.New("encoder has no media types")
+	}
+	entry := &encoderEntry{encoder: encoder}
+	for _, mediaType := range encoder.MediaTypes() {
+		if mediaType == "" {
+			return errors.New("media type cannot be empty")
+		}
+		entry.mediaTypes = append(entry.mediaTypes, strings.ToLower(mediaType))
+	}
+	for _, mediaType := range entry.mediaTypes {
+		r.forget(mediaType)
+		r.setEncodeFunc(mediaType, encoder.Encode)
+		if valueEncoder, ok := encoder.(ValueEncoder); ok {
+			r.setValueEncodeFunc(mediaType, valueEncoder.EncodeValue)
+		}
+	}
+	r.entries = append(r.entries, entry)
+	r.update()
+	return nil
+}
+
+// Alias registers alias as additional media type for the encoder of mediaType (e.g. "application/x-yaml" for
+// "application/yaml"), the value encoder of mediaType is aliased as well.
+func (r *EncoderRegistry) Alias(alias, mediaType string) error {
+	if alias == "" {
+		return errors.New("alias cannot be empty")
+	}
+	alias, mediaType = strings.ToLower(alias), strings.ToLower(mediaType)
+	f, ok := r.options.Encoders[mediaType]
+	if !ok {
+		return errors.Errorf("no encoder for media type %q", mediaType)
+	}
+	r.Remove(alias)
+	r.setEncodeFunc(alias, f)
+	if valueEncoder, ok := r.options.ValueEncoders[mediaType]; ok {
+		r.setValueEncodeFunc(alias, valueEncoder)
+	}
+	if entry := r.entry(mediaType); entry != nil {
+		entry.mediaTypes = append(entry.mediaTypes, alias)
+	}
+	r.update()
+	return nil
+}
+
+// Prefer moves the encoder of the media type to the front of the registry, so it will be used if the client accepts
+// several media types equally (and no encoder has a higher weight).
+func (r *EncoderRegistry) Prefer(mediaType string) error {
+	mediaType = strings.ToLower(mediaType)
+	entry := r.entry(mediaType)
+	if entry == nil {
+		f, ok := r.options.Encoders[mediaType]
+		if !ok {
+			return errors.Errorf("no encoder for media type %q", mediaType)
+		}
+		// register the encoder that was only present in the Encoders map
+		entry = &encoderEntry{encoder: WrapEncodeFunc(f, mediaType), mediaTypes: []string{mediaType}}
+		r.entries = append(r.entries, entry)
+	}
+	entries := make([]*encoderEntry, 0, len(r.entries))
+	entries = append(entries, entry)
+	for _, e := range r.entries {
+		if e != entry {
+			entries = append(entries, e)
+		}
+	}
+	r.entries = entries
+	r.update()
+	return nil
+}
+
+// Remove removes the encoder and the value encoder for the media type.
+func (r *EncoderRegistry) Remove(mediaType string) {
+	mediaType = strings.ToLower(mediaType)
+	delete(r.options.Encoders, mediaType)
+	delete(r.options.ValueEncoders, mediaType)
+	r.forget(mediaType)
+	r.update()
+}
+
+// Lookup returns the Encoder for the media type, encoders that are only present in the Encoders of the Options are
+// wrapped using WrapEncodeFunc. If there is no encoder for the media type nil is returned.
+func (r *EncoderRegistry) Lookup(mediaType string) Encoder {
+	mediaType = strings.ToLower(mediaType)
+	if entry := r.entry(mediaType); entry != nil {
+		if _, ok := r.options.Encoders[mediaType]; ok {
+			return entry.encoder
+		}
+	}
+	f := lookupEncoder(r.options.Encoders, mediaType)
+	if f == nil {
+		return nil
+	}
+	return WrapEncodeFunc(f, mediaType)
+}
+
+// List returns the encoders in the order of preference, it can be used to document the supported media types.
+// Registered encoders with a negative weight rank after the unregistered encoders (that have the weight 0).
+func (r *EncoderRegistry) List() []EncoderInfo {
+	var before, after []EncoderInfo
+	for _, entry := range r.rankedEntries() {
+		mediaTypes := r.available(entry.mediaTypes)
+		if len(mediaTypes) == 0 {
+			continue
+		}
+		info := EncoderInfo{
+			MediaTypes: mediaTypes,
+			Charset:    entry.encoder.Charset(),
+			Weight:     entry.encoder.Weight(),
+			Registered: true,
+		}
+		if info.Weight < 0 {
+			after = append(after, info)
+		} else {
+			before = append(before, info)
+		}
+	}
+	for _, mediaType := range r.unregisteredMediaTypes() {
+		before = append(before, EncoderInfo{MediaTypes: []string{mediaType}})
+	}
+	return append(before, after...)
+}
+
+// MediaTypes returns the media types of the encoders in the order of preference.
+func (r *EncoderRegistry) MediaTypes() []string {
+	mediaTypes := r.orderedMediaTypes()
+	if len(mediaTypes) == 0 {
+		return nil
+	}
+	return append([]string(nil), mediaTypes...)
+}
+
+// orderedMediaTypes returns the cached media types in the order of preference without the media types that have been
+// removed from the Encoders of the Options directly. The returned slice must not be modified.
+func (r *EncoderRegistry) orderedMediaTypes() []string {
+	for i, mediaType := range r.mediaTypes {
+		if _, ok := r.options.Encoders[mediaType]; ok {
+			continue
+		}
+		mediaTypes := make([]string, i, len(r.mediaTypes))
+		copy(mediaTypes, r.mediaTypes[:i])
+		return append(mediaTypes, r.available(r.mediaTypes[i+1:])...)
+	}
+	return r.mediaTypes
+}
+
+// update rebuilds the cached media types.
+func (r *EncoderRegistry) update() {
+	var mediaTypes []string
+	for _, info := range r.List() {
+		mediaTypes = append(mediaTypes, info.MediaTypes...)
+	}
+	// limit the capacity, so appending to the cached media types never writes into the cache
+	r.mediaTypes = mediaTypes[:len(mediaTypes):len(mediaTypes)]
+}
+
+// charset returns the charset of the encoder that is registered for the media type.
+func (r *EncoderRegistry) charset(mediaType string) string {
+	if entry := r.entry(mediaType); entry != nil {
+		return entry.encoder.Charset()
+	}
+	return ""
+}
+
+// entry returns the entry that is registered for the media type.
+func (r *EncoderRegistry) entry(mediaType string) *encoderEntry {
+	for _, entry := range r.entries {
+		for _, m := range entry.mediaTypes {
+			if m == mediaType {
+				return entry
+			}
+		}
+	}
+	return nil
+}
+
+// forget removes the media type from the entries, without touching the Encoders of the Options.
+func (r *EncoderRegistry) forget(mediaType string) {
+	entries := r.entries[:0]
+	for _, entry := range r.entries {
+		mediaTypes := entry.mediaTypes[:0]
+		for _, m := range entry.mediaTypes {
+			if m != mediaType {
+				mediaTypes = append(mediaTypes, m)
+			}
+		}
+		entry.mediaTypes = mediaTypes
+		if len(entry.mediaTypes) > 0 {
+			entries = append(entries, entry)
+		}
+	}
+	r.entries = entries
+}
+
+// setEncodeFunc sets the EncodeFunc in the Encoders of the Options.
+func (r *EncoderRegistry) setEncodeFunc(mediaType string, f EncodeFunc) {
+	if r.options.Encoders == nil {
+		r.options.Encoders = make(map[string]EncodeFunc)
+	}
+	r.options.Encoders[mediaType] = f
+}
+
+// setValueEncodeFunc sets the ValueEncodeFunc in the ValueEncoders of the Options.
+func (r *EncoderRegistry) setValueEncodeFunc(mediaType string, f ValueEncodeFunc) {
+	if r.options.ValueEncoders == nil {
+		r.options.ValueEncoders = make(map[string]ValueEncodeFunc)
+	}
+	r.options.ValueEncoders[mediaType] = f
+}
+
+// rankedEntries returns the entries sorted by their weight, entries with the same weight keep their order.
+func (r *EncoderRegistry) rankedEntries() []*encoderEntry {
+	entries := make([]*encoderEntry, len(r.entries))
+	copy(entries, r.entries)
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].encoder.Weight() > entries[j].encoder.Weight()
+	})
+	return entries
+}
+
+// available returns the media types that are still present in the Encoders of the Options (they might have been
+// removed from the map directly).
+func (r *EncoderRegistry) available(mediaTypes []string) []string {
+	result := make([]string, 0, len(mediaTypes))
+	for _, mediaType := range mediaTypes {
+		if _, ok := r.options.Encoders[mediaType]; ok && !isSuffixKey(mediaType) {
+			result = append(result, mediaType)
+		}
+	}
+	return result
+}
+
+// preferContentType moves the content type to the front of the media types, so it wins ties (e.g. for "*/*").
+func preferContentType(mediaTypes []string, contentType string) []string {
+	contentType = strings.ToLower(contentType)
+	for i, mediaType := range mediaTypes {
+		if mediaType == contentType {
+			copy(mediaTypes[1:i+1], mediaTypes[:i])
+			mediaTypes[0] = contentType
+			break
+		}
+	}
+	return mediaTypes
+}
+
+// unregisteredMediaTypes returns the media types that are only present in the Encoders of the Options, the content
+// type of the fallback encoder comes first, the others are sorted.
+func (r *EncoderRegistry) unregisteredMediaTypes() []string {
+	var mediaTypes []string
+	for _, mediaType := range encoderContentTypes(r.options.Encoders) {
+		if r.entry(mediaType) == nil {
+			mediaTypes = append(mediaTypes, mediaType)
+		}
+	}
+	if r.options.FallbackEncoderFunc != nil {
+		_, fallbackContentType := r.options.FallbackEncoderFunc()
+		mediaTypes = preferContentType(mediaTypes, fallbackContentType)
+	}
+	return mediaTypes
+}
@@ -0,0 +1,241 @@
+package httphandler_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/Eun/go-hit"
+
+	"github.com/pkg/errors"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/talon-one/go-httphandler"
+)
+
+func TestEncoderRegistry(t *testing.T) {
+	newServer := func(t *testing.T) (*httphandler.Handler, *httptest.Server) {
+		h := httphandler.New(&httphandler.Options{
+			Encoders: map[string]httphandler.EncodeFunc{
+				"application/json": stringEncoder("json"),
+				"application/xml":  stringEncoder("xml"),
+			},
+			FallbackEncoderFunc: func() (httphandler.EncodeFunc, string) {
+				return stringEncoder("fallback"), "application/json"
+			},
+		})
+		s := httptest.NewServer(h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+			return &httphandler.HandlerError{
+				StatusCode:  http.StatusBadRequest,
+				PublicError: errors.New("invalid"),
+			}
+		}))
+		t.Cleanup(s.Close)
+		return h, s
+	}
+	expect := func(t *testing.T, s *httptest.Server, accept, contentType, body string) {
+		hit.Test(t,
+			hit.Get(s.URL),
+			hit.Send().Headers("Accept").Add(accept),
+			hit.Expect().Headers("Content-Type").Equal(contentType),
+			hit.Expect().Body().String().Equal(body),
+		)
+	}
+
+	t.Run("register", func(t *testing.T) {
+		h, s := newServer(t)
+		require.NoError(t, h.Encoders().Register(
+			httphandler.WrapEncodeFunc(stringEncoder("yaml"), "application/yaml", "text/yaml").WithCharset("utf-8"),
+		))
+		expect(t, s, "text/yaml", "text/yaml; charset=utf-8", "yaml")
+		// registered encoders are preferred by the server
+		expect(t, s, "*/*", "application/yaml; charset=utf-8", "yaml")
+		expect(t, s, "application/json, application/yaml", "application/json", "json")
+
+		require.Equal(t, []httphandler.EncoderInfo{
+			{MediaTypes: []string{"application/yaml", "text/yaml"}, Charset: "utf-8", Registered: true},
+			{MediaTypes: []string{"application/json"}},
+			{MediaTypes: []string{"application/xml"}},
+		}, h.Encoders().List())
+	})
+
+	t.Run("weight", func(t *testing.T) {
+		h, s := newServer(t)
+		require.NoError(t, h.Encoders().Register(httphandler.WrapEncodeFunc(stringEncoder("yaml"), "application/yaml").WithWeight(-1)))
+		require.NoError(t, h.Encoders().Register(httphandler.WrapEncodeFunc(stringEncoder("csv"), "text/csv")))
+		require.NoError(t, h.Encoders().Register(httphandler.WrapEncodeFunc(stringEncoder("cbor"), "application/cbor").WithWeight(1)))
+		require.Equal(t, []string{"application/cbor", "text/csv", "application/json", "application/xml", "application/yaml"},
+			h.Encoders().MediaTypes())
+		expect(t, s, "*/*", "application/cbor", "cbor")
+		expect(t, s, "application/yaml, application/json", "application/yaml", "yaml")
+	})
+
+	t.Run("prefer", func(t *testing.T) {
+		h, s := newServer(t)
+		require.NoError(t, h.Encoders().Register(httphandler.WrapEncodeFunc(stringEncoder("yaml"), "application/yaml")))
+		require.NoError(t, h.Encoders().Prefer("application/xml"))
+		expect(t, s, "*/*", "application/xml", "xml")
+		require.Equal(t, []string{"application/xml", "application/yaml", "application/json"}, h.Encoders().MediaTypes())
+		require.EqualError(t, h.Encoders().Prefer("text/csv"), `no encoder for media type "text/csv"`)
+	})
+
+	t.Run("alias", func(t *testing.T) {
+		h, s := newServer(t)
+		require.NoError(t, h.Encoders().Register(httphandler.WrapEncodeFunc(stringEncoder("yaml"), "application/yaml")))
+		require.NoError(t, h.Encoders().Alias("application/x-yaml", "application/yaml"))
+		require.NoError(t, h.Encoders().Alias("text/x-json", "application/json"))
+		expect(t, s, "application/x-yaml", "application/x-yaml", "yaml")
+		expect(t, s, "text/x-json", "text/x-json", "json")
+		require.Equal(t, []string{"application/yaml", "application/x-yaml"}, h.Encoders().List()[0].MediaTypes)
+		require.EqualError(t, h.Encoders().Alias("text/x-csv", "text/csv"), `no encoder for media type "text/csv"`)
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		h, s := newServer(t)
+		require.NoError(t, h.Encoders().Register(httphandler.WrapEncodeFunc(stringEncoder("yaml"), "application/yaml")))
+		h.Encoders().Remove("application/yaml")
+		h.Encoders().Remove("application/xml")
+		expect(t, s, "application/xml, application/yaml", "application/json", "fallback")
+		require.Equal(t, []string{"application/json"}, h.Encoders().MediaTypes())
+	})
+
+	t.Run("lookup", func(t *testing.T) {
+		h, _ := newServer(t)
+		encoder := httphandler.WrapEncodeFunc(stringEncoder("yaml"), "application/yaml").WithCharset("utf-8")
+		require.NoError(t, h.Encoders().Register(encoder))
+		require.Equal(t, encoder, h.Encoders().Lookup("application/yaml"))
+		require.Equal(t, []string{"application/json"}, h.Encoders().Lookup("application/json").MediaTypes())
+		require.Nil(t, h.Encoders().Lookup("text/csv"))
+
+		// SetEncoder replaces the registered encoder
+		require.NoError(t, h.SetEncoder("application/yaml", stringEncoder("yaml")))
+		require.Empty(t, h.Encoders().Lookup("application/yaml").Charset())
+	})
+
+	t.Run("invalid encoders", func(t *testing.T) {
+		h, _ := newServer(t)
+		require.EqualError(t, h.Encoders().Register(nil), "encoder cannot be nil")
+		require.EqualError(t, h.Encoders().Register(httphandler.WrapEncodeFunc(stringEncoder("none"))), "encoder has no media types")
+		require.EqualError(t, h.Encoders().Register(httphandler.WrapEncodeFunc(stringEncoder("none"), "")), "media type cannot be empty")
+	})
+}
+
+func TestEncoderRegistryPrefersFallbackEncoder(t *testing.T) {
+	h := httphandler.New(&httphandler.Options{
+		Encoders: map[string]httphandler.EncodeFunc{
+			"application/json": stringEncoder("json"),
+			"application/xml":  stringEncoder("xml"),
+			"text/plain":       stringEncoder("text"),
+		},
+		FallbackEncoderFunc: func() (httphandler.EncodeFunc, string) {
+			return stringEncoder("fallback"), "Application/XML"
+		},
+	})
+	s := httptest.NewServer(h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		return &httphandler.HandlerError{
+			StatusCode:  http.StatusBadRequest,
+			PublicError: errors.New("invalid"),
+		}
+	}))
+	defer s.Close()
+
+	require.Equal(t, []string{"application/xml", "application/json", "text/plain"}, h.Encoders().MediaTypes())
+
+	// the fallback encoder wins ties
+	hit.Test(t,
+		hit.Get(s.URL),
+		hit.Send().Headers("Accept").Add("*/*"),
+		hit.Expect().Headers("Content-Type").Equal("application/xml"),
+		hit.Expect().Body().String().Equal("xml"),
+	)
+	// other ties are resolved in the order of the media types
+	hit.Test(t,
+		hit.Get(s.URL),
+		hit.Send().Headers("Accept").Add("application/json, text/plain"),
+		hit.Expect().Headers("Content-Type").Equal("application/json"),
+		hit.Expect().Body().String().Equal("json"),
+	)
+	// but not over explicit client preferences
+	hit.Test(t,
+		hit.Get(s.URL),
+		hit.Send().Headers("Accept").Add("application/xml;q=0.5, text/plain"),
+		hit.Expect().Headers("Content-Type").Equal("text/plain"),
+		hit.Expect().Body().String().Equal("text"),
+	)
+}
+
+// stringValueEncoder is an Encoder that also encodes values, it writes s for errors and values.
+type stringValueEncoder struct {
+	httphandler.Encoder
+	s string
+}
+
+func (e *stringValueEncoder) EncodeValue(w http.ResponseWriter, _ *http.Request, _ interface{}) error {
+	_, err := io.WriteString(w, e.s)
+	return err
+}
+
+func TestEncoderRegistryValueEncoders(t *testing.T) {
+	h := httphandler.New(nil)
+	s := httptest.NewServer(h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		return h.Respond(w, r, http.StatusOK, "Joe")
+	}))
+	defer s.Close()
+	expect := func(t *testing.T, accept, contentType, body string) {
+		hit.Test(t,
+			hit.Get(s.URL),
+			hit.Send().Headers("Accept").Add(accept),
+			hit.Expect().Status().Equal(http.StatusOK),
+			hit.Expect().Headers("Content-Type").Equal(contentType),
+			hit.Expect().Body().String().Equal(body),
+		)
+	}
+
+	require.NoError(t, h.Encoders().Register(&stringValueEncoder{
+		Encoder: httphandler.WrapEncodeFunc(stringEncoder("yaml"), "application/yaml"),
+		s:       "yaml",
+	}))
+	expect(t, "application/yaml", "application/yaml", "yaml")
+
+	// aliases include the value encoder
+	require.NoError(t, h.Encoders().Alias("application/x-yaml", "application/yaml"))
+	expect(t, "application/x-yaml", "application/x-yaml", "yaml")
+
+	// encoders that do not encode values keep the value encoder of their media type
+	require.NoError(t, h.Encoders().Register(httphandler.WrapEncodeFunc(stringEncoder("json"), "application/json")))
+	expect(t, "application/json", "application/json", "\"Joe\"\n")
+
+	// removing a media type removes its value encoder
+	h.Encoders().Remove("application/yaml")
+	expect(t, "application/yaml", "application/json", "\"Joe\"\n")
+	expect(t, "application/x-yaml", "application/x-yaml", "yaml")
+}
+
+func TestEncoderRegistryConcurrentRequests(t *testing.T) {
+	copied := *httphandler.DefaultOptions()
+	for _, h := range []*httphandler.Handler{
+		httphandler.New(&httphandler.Options{}),
+		httphandler.New(&copied),
+		httphandler.DefaultHandler,
+	} {
+		handler := h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+			return httphandler.NotFound("")
+		})
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+				req.Header.Set("Accept", "*/*")
+				rec := httptest.NewRecorder()
+				handler(rec, req)
+				require.Equal(t, http.StatusNotFound, rec.Code)
+			}()
+		}
+		wg.Wait()
+	}
+}
@@ -0,0 +1,188 @@
+package httphandler
+
+import (
+	"encoding"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultFormDecoder implements the default decoder for "application/x-www-form-urlencoded" bodies.
+// The target can be a *url.Values, a *map[string][]string, a *map[string]string (that gets the first value of every
+// field) or a pointer to a struct. Struct fields are filled from the form field that is named by their "form" tag (or
+// their name if they have no tag, "-" skips the field), supported are strings, bools, numbers, types that implement
+// encoding.TextUnmarshaler and slices of them.
+//
+// Example:
+//
+//	type Login struct {
+//	    User     string `form:"user"`
+//	    Remember bool   `form:"remember"`
+//	}
+func DefaultFormDecoder() DecodeFunc {
+	return func(r *http.Request, v interface{}) error {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return &DecodeError{Offset: formErrorOffset(string(body), err), Msg: err.Error()}
+		}
+		return decodeForm(values, v)
+	}
+}
+
+// formErrorOffset returns the offset of the invalid escape sequence in the body, or -1.
+func formErrorOffset(body string, err error) int64 {
+	var escapeError url.EscapeError
+	if !errors.As(err, &escapeError) {
+		return -1
+	}
+	return int64(strings.Index(body, string(escapeError)))
+}
+
+// decodeForm stores the form values in v.
+func decodeForm(values url.Values, v interface{}) error {
+	switch target := v.(type) {
+	case *url.Values:
+		*target = values
+		return nil
+	case *map[string][]string:
+		*target = values
+		return nil
+	case *map[string]string:
+		*target = make(map[string]string, len(values))
+		for name := range values {
+			(*target)[name] = values.Get(name)
+		}
+		return nil
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.Errorf("unable to decode form into %T", v)
+	}
+	return decodeFormStruct(values, rv.Elem())
+}
+
+// decodeFormStruct stores the form values in the fields of the struct.
+func decodeFormStruct(values url.Values, rv reflect.Value) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.PkgPath != "" {
+			// unexported field
+			continue
+		}
+		name := field.Name
+		if tag, ok := field.Tag.Lookup("form"); ok {
+			name = strings.Split(tag, ",")[0]
+		}
+		if name == "-" {
+			continue
+		}
+		fieldValues, ok := values[name]
+		if !ok || len(fieldValues) == 0 {
+			continue
+		}
+		if err := setFormValue(rv.Field(i), fieldValues); err != nil {
+			if errors.Is(err, errUnsupportedFormField) {
+				return err
+			}
+			return &DecodeError{Offset: -1, Msg: formValueErrorMessage(name, rv.Field(i))}
+		}
+	}
+	return nil
+}
+
+// formValueErrorMessage describes an invalid value of the form field like the JSON decoder, without the parse error
+// that contains Go types (e.g. `invalid value for "age": expected integer`).
+func formValueErrorMessage(name string, field reflect.Value) string {
+	t := field.Type()
+	if field.Kind() == reflect.Slice && !isTextUnmarshaler(field) {
+		t = t.Elem()
+	}
+	msg := fmt.Sprintf("invalid value for %q", name)
+	// every form value is a string, so only the types that need to be parsed are mentioned
+	switch expected := jsonType(t); expected {
+	case "boolean", "integer", "number":
+		msg += ": expected " + expected
+	}
+	return msg
+}
+
+// setFormValue sets the field to the form values, slices get all values, other fields the first one.
+func setFormValue(field reflect.Value, values []string) error {
+	if field.Kind() == reflect.Slice && !isTextUnmarshaler(field) {
+		slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setFormString(slice.Index(i), value); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+		return nil
+	}
+	return setFormString(field, values[0])
+}
+
+// isTextUnmarshaler reports whether a pointer to the value implements encoding.TextUnmarshaler.
+func isTextUnmarshaler(field reflect.Value) bool {
+	_, ok := field.Addr().Interface().(encoding.TextUnmarshaler)
+	return ok
+}
+
+// errUnsupportedFormField is returned by setFormString if the type of the field is not supported.
+var errUnsupportedFormField = errors.New("unsupported form field")
+
+// setFormString parses the string into the value.
+func setFormString(field reflect.Value, value string) error {
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		return setFormString(field.Elem(), value)
+	}
+	if u, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(value))
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(n)
+	default:
+		return errors.Wrapf(errUnsupportedFormField, "type %s", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package httphandler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Eun/go-hit"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/talon-one/go-httphandler"
+)
+
+type formLogin struct {
+	User     string    `form:"user"`
+	Remember bool      `form:"remember"`
+	Attempts *int      `form:"attempts"`
+	Scopes   []string  `form:"scope"`
+	Since    time.Time `form:"since"`
+	Ignored  string    `form:"-"`
+}
+
+func TestFormDecoder(t *testing.T) {
+	h := httphandler.New(nil)
+	var login formLogin
+	var values url.Values
+	mux := http.NewServeMux()
+	mux.HandleFunc("/struct", h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		login = formLogin{}
+		if err := h.Decode(r, &login); err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}))
+	mux.HandleFunc("/values", h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		if err := h.Decode(r, &values); err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}))
+
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	t.Run("struct", func(t *testing.T) {
+		hit.Test(t,
+			hit.Post(hit.JoinURL(s.URL, "struct")),
+			hit.Send().Headers("Content-Type").Add("application/x-www-form-urlencoded"),
+			hit.Send().Body().String("user=joe&remember=true&attempts=3&scope=read&scope=write&since=2020-01-02T03:04:05Z&Ignored=x"),
+			hit.Expect().Status().Equal(http.StatusNoContent),
+		)
+		attempts := 3
+		require.Equal(t, formLogin{
+			User:     "joe",
+			Remember: true,
+			Attempts: &attempts,
+			Scopes:   []string{"read", "write"},
+			Since:    time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		}, login)
+	})
+
+	t.Run("values", func(t *testing.T) {
+		hit.Test(t,
+			hit.Post(hit.JoinURL(s.URL, "values")),
+			hit.Send().Headers("Content-Type").Add("application/x-www-form-urlencoded"),
+			hit.Send().Body().String("a=1&a=2&b=3"),
+			hit.Expect().Status().Equal(http.StatusNoContent),
+		)
+		require.Equal(t, url.Values{"a": {"1", "2"}, "b": {"3"}}, values)
+	})
+
+	t.Run("invalid value", func(t *testing.T) {
+		hit.Test(t,
+			hit.Post(hit.JoinURL(s.URL, "struct")),
+			hit.Send().Headers("Content-Type").Add("application/x-www-form-urlencoded"),
+			hit.Send().Body().String("user=joe&remember=maybe"),
+			hit.Expect().Status().Equal(http.StatusBadRequest),
+			hit.Expect().Body().JSON().JQ(".Error.Offset").Equal(-1),
+			hit.Expect().Body().JSON().JQ(".Error.Msg").Equal(`invalid value for "remember": expected boolean`),
+		)
+	})
+
+	t.Run("invalid escape", func(t *testing.T) {
+		hit.Test(t,
+			hit.Post(hit.JoinURL(s.URL, "values")),
+			hit.Send().Headers("Content-Type").Add("application/x-www-form-urlencoded"),
+			hit.Send().Body().String("a=1&b=%zz"),
+			hit.Expect().Status().Equal(http.StatusBadRequest),
+			hit.Expect().Body().JSON().JQ(".Error.Offset").Equal(6),
+		)
+	})
+}
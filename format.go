@@ -0,0 +1,138 @@
+package httphandler
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// FormatSource extracts the format the client asked for from the request, e.g. from a query parameter.
+// FormatSources are consulted before the clients Accept header, see Options.FormatSources.
+type FormatSource interface {
+	// Format returns the requested format, this can be an alias (e.g. "json") or a content type
+	// (e.g. "application/json"). If the request does not specify a format an empty string should be returned.
+	Format(r *http.Request) string
+}
+
+// VaryFormatSource can be implemented by a FormatSource that reads the format from request headers.
+// If the source was consulted during negotiation the headers will be added to the Vary header of the response.
+type VaryFormatSource interface {
+	FormatSource
+	// VaryHeaders returns the names of the request headers the source depends on.
+	VaryHeaders() []string
+}
+
+// The FormatSourceFunc type is an adapter to allow the use of ordinary functions as FormatSource.
+type FormatSourceFunc func(r *http.Request) string
+
+// Format calls f(r).
+func (f FormatSourceFunc) Format(r *http.Request) string {
+	return f(r)
+}
+
+type queryFormatSource struct {
+	parameter string
+}
+
+func (s queryFormatSource) Format(r *http.Request) string {
+	return r.URL.Query().Get(s.parameter)
+}
+
+// FormatFromQuery returns a FormatSource that reads the format from the specified query parameter.
+//
+// Example:
+//
+//	// GET /users?format=xml
+//	FormatFromQuery("format")
+func FormatFromQuery(parameter string) FormatSource {
+	return queryFormatSource{parameter: parameter}
+}
+
+type pathExtensionFormatSource struct{}
+
+func (pathExtensionFormatSource) Format(r *http.Request) string {
+	return strings.TrimPrefix(path.Ext(r.URL.Path), ".")
+}
+
+// FormatFromPathExtension returns a FormatSource that reads the format from the extension of the request path.
+// Since an extension cannot contain a content type it must be one of the FormatAliases.
+//
+// Example:
+//
+//	// GET /users.xml
+//	FormatFromPathExtension()
+func FormatFromPathExtension() FormatSource {
+	return pathExtensionFormatSource{}
+}
+
+type headerFormatSource struct {
+	header string
+}
+
+func (s headerFormatSource) Format(r *http.Request) string {
+	return r.Header.Get(s.header)
+}
+
+func (s headerFormatSource) VaryHeaders() []string {
+	return []string{s.header}
+}
+
+// FormatFromHeader returns a FormatSource that reads the format from the specified request header.
+//
+// Example:
+//
+//	// X-Format: xml
+//	FormatFromHeader("X-Format")
+func FormatFromHeader(header string) FormatSource {
+	return headerFormatSource{header: header}
+}
+
+// resolveFormat returns the encoder and content type for the specified format.
+// The format must either be one of the FormatAliases or one of the content types of the Encoders, any other format is
+// rejected so that clients cannot select arbitrary content types.
+func resolveFormat(options *Options, format string) (encoder EncodeFunc, contentType string) {
+	contentType, alias := formatContentType(options, format)
+	switch {
+	case contentType == "":
+		return nil, ""
+	case alias:
+		if f := lookupEncoder(options.Encoders, contentType); f != nil {
+			return f, contentType
+		}
+		return nil, ""
+	}
+	if f, ok := options.Encoders[contentType]; ok {
+		return f, contentType
+	}
+	return nil, ""
+}
+
+// formatContentType returns the content type the format refers to, alias reports whether the format is one of the
+// FormatAliases. Formats that are structured syntax suffixes (e.g. "+json") are rejected.
+func formatContentType(options *Options, format string) (contentType string, alias bool) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "" {
+		return "", false
+	}
+	if contentType, ok := options.FormatAliases[format]; ok {
+		return strings.ToLower(contentType), true
+	}
+	if isSuffixKey(format) {
+		return "", false
+	}
+	return format, false
+}
+
+// getFormatOverride returns the encoder and content type of the first FormatSource that specifies a valid format.
+// It also returns the request headers of the sources that have been consulted, so they can be added to the Vary header.
+func getFormatOverride(options *Options, r *http.Request) (encoder EncodeFunc, contentType string, vary []string) {
+	for _, source := range options.FormatSources {
+		if v, ok := source.(VaryFormatSource); ok {
+			vary = append(vary, v.VaryHeaders()...)
+		}
+		if f, contentType := resolveFormat(options, source.Format(r)); f != nil {
+			return f, contentType, vary
+		}
+	}
+	return nil, "", vary
+}
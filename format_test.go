@@ -0,0 +1,150 @@
+package httphandler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Eun/go-hit"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/talon-one/go-httphandler"
+)
+
+func TestFormatSources(t *testing.T) {
+	h := httphandler.New(&httphandler.Options{
+		Encoders: map[string]httphandler.EncodeFunc{
+			"application/json": stringEncoder("json"),
+			"application/xml":  stringEncoder("xml"),
+			"text/html":        stringEncoder("html"),
+		},
+		FormatSources: []httphandler.FormatSource{
+			httphandler.FormatFromQuery("format"),
+			httphandler.FormatFromHeader("X-Format"),
+			httphandler.FormatFromPathExtension(),
+		},
+	})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		return &httphandler.HandlerError{}
+	}))
+	mux.HandleFunc("/content-type", h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		return &httphandler.HandlerError{
+			ContentType: "text/html",
+		}
+	}))
+
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	tests := []struct {
+		name                string
+		path                string
+		header              map[string]string
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			name:                "query parameter alias",
+			path:                "/?format=xml",
+			expectedContentType: "application/xml",
+			expectedBody:        "xml",
+		},
+		{
+			name:                "query parameter content type",
+			path:                "/?format=text/html",
+			expectedContentType: "text/html",
+			expectedBody:        "html",
+		},
+		{
+			name:                "path extension",
+			path:                "/users.xml",
+			expectedContentType: "application/xml",
+			expectedBody:        "xml",
+		},
+		{
+			name:                "header",
+			path:                "/",
+			header:              map[string]string{"X-Format": "HTML"},
+			expectedContentType: "text/html",
+			expectedBody:        "html",
+		},
+		{
+			name:                "override takes precedence over accept header",
+			path:                "/?format=json",
+			header:              map[string]string{"Accept": "application/xml"},
+			expectedContentType: "application/json",
+			expectedBody:        "json",
+		},
+		{
+			name:                "sources are consulted in order",
+			path:                "/users.html?format=xml",
+			header:              map[string]string{"X-Format": "json"},
+			expectedContentType: "application/xml",
+			expectedBody:        "xml",
+		},
+		{
+			name:                "invalid formats are ignored",
+			path:                "/users.exe?format=image/png",
+			header:              map[string]string{"Accept": "text/html"},
+			expectedContentType: "text/html",
+			expectedBody:        "html",
+		},
+		{
+			name:                "content type of the handler error takes precedence",
+			path:                "/content-type?format=xml",
+			expectedContentType: "text/html",
+			expectedBody:        "html",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			steps := []hit.IStep{
+				hit.Get(s.URL + test.path),
+			}
+			for k, v := range test.header {
+				steps = append(steps, hit.Send().Headers(k).Add(v))
+			}
+			steps = append(steps,
+				hit.Expect().Status().Equal(http.StatusInternalServerError),
+				hit.Expect().Headers("Content-Type").Equal(test.expectedContentType),
+				hit.Expect().Body().String().Equal(test.expectedBody),
+			)
+			hit.Test(t, steps...)
+		})
+	}
+}
+
+func TestSetFormatAlias(t *testing.T) {
+	h := httphandler.New(nil)
+	h.SetFormatSources(httphandler.FormatFromQuery("format"))
+	require.NoError(t, h.SetFormatAlias("TXT", "text/html"))
+	require.NoError(t, h.SetFormatAlias("png", "image/png"))
+	require.EqualError(t, h.SetFormatAlias("", "text/html"), "alias cannot be empty")
+	require.EqualError(t, h.SetFormatAlias("txt", ""), "content-type cannot be empty")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		return &httphandler.HandlerError{}
+	}))
+
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	t.Run("alias", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(s.URL+"/?format=txt"),
+			hit.Expect().Headers("Content-Type").Equal("text/html"),
+		)
+	})
+
+	t.Run("alias without encoder", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(s.URL+"/?format=png"),
+			hit.Expect().Headers("Content-Type").Equal("application/json"),
+		)
+	})
+}
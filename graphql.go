@@ -0,0 +1,113 @@
+package httphandler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// GraphQLResponseContentType is the media type of GraphQL responses defined by the GraphQL over HTTP specification.
+const GraphQLResponseContentType = "application/graphql-response+json"
+
+// GraphQLError is a PublicError that specifies the members of the error that is rendered by GraphQLEncoder, other
+// encoders render the Message.
+type GraphQLError struct {
+	// Message is the error message for the client.
+	Message string
+	// Code is the error code that is send in the extensions. If not specified the code will be derived from the
+	// status code (e.g. "BAD_REQUEST" for 400).
+	Code string
+	// Extensions are additional members of the extensions of the error.
+	Extensions map[string]interface{}
+}
+
+func (e *GraphQLError) Error() string {
+	return e.Message
+}
+
+// graphQLCode returns the error code for the status code, the code is the upper snake case status text
+// (e.g. "NOT_FOUND"), except for 401 which results in "UNAUTHENTICATED".
+func graphQLCode(statusCode int) string {
+	if statusCode == http.StatusUnauthorized {
+		return "UNAUTHENTICATED"
+	}
+	text := statusText(statusCode)
+	if text == "" {
+		return "INTERNAL_SERVER_ERROR"
+	}
+	return strings.ToUpper(strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(text))
+}
+
+// graphQLStatusCode returns the status code for a GraphQL response that has no data: the GraphQL over HTTP
+// specification requires a 4xx or 5xx status code, so every other status code is replaced by 500.
+func graphQLStatusCode(statusCode int) int {
+	if statusCode >= 400 && statusCode < 600 {
+		return statusCode
+	}
+	return http.StatusInternalServerError
+}
+
+// GraphQLEncoder implements an encoder for transport-level errors of GraphQL endpoints
+// (e.g. {"errors":[{"message":"...","extensions":{"code":"BAD_REQUEST","requestUUID":"..."}}]}).
+// It is not part of the default Encoders, register it for GraphQLResponseContentType if the handlers serve GraphQL
+// clients. Since the response has no data the status code will be 4xx or 5xx as the GraphQL over HTTP specification
+// requires, other status codes are sent as 500.
+// If the PublicError is a *GraphQLError its code and extensions are used.
+//
+// Example:
+//
+//	handler.SetEncoder(GraphQLResponseContentType, GraphQLEncoder())
+func GraphQLEncoder() EncodeFunc {
+	return func(w http.ResponseWriter, r *http.Request, e *WireError) error {
+		var graphQLError GraphQLError
+		var ge *GraphQLError
+		if errors.As(e.Error, &ge) {
+			graphQLError = *ge
+		} else if e.Error != nil {
+			graphQLError.Message = e.Error.Error()
+		}
+		if graphQLError.Code == "" {
+			graphQLError.Code = graphQLCode(e.StatusCode)
+		}
+
+		extensions := make(map[string]interface{}, len(graphQLError.Extensions)+2) //nolint:gomnd // code and requestUUID
+		for name, value := range graphQLError.Extensions {
+			extensions[name] = value
+		}
+		extensions["code"] = graphQLError.Code
+		if e.RequestUUID != "" {
+			extensions["requestUUID"] = e.RequestUUID
+		}
+
+		type graphQLErrorObject struct {
+			Message    string                 `json:"message"`
+			Extensions map[string]interface{} `json:"extensions"`
+		}
+		return json.NewEncoder(w).Encode(struct {
+			Errors []graphQLErrorObject `json:"errors"`
+		}{
+			Errors: []graphQLErrorObject{{
+				Message:    graphQLError.Message,
+				Extensions: extensions,
+			}},
+		})
+	}
+}
+
+// statusCodeRules maps media types to a function that returns the status code that must be used for that media type.
+var statusCodeRules = map[string]func(statusCode int) int{
+	GraphQLResponseContentType: graphQLStatusCode,
+}
+
+// applyStatusCodeRule returns the status code that must be used for the content type, see statusCodeRules.
+func applyStatusCodeRule(contentType string, statusCode int) int {
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	if rule, ok := statusCodeRules[strings.TrimSpace(contentType)]; ok {
+		return rule(statusCode)
+	}
+	return statusCode
+}
@@ -0,0 +1,123 @@
+package httphandler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Eun/go-hit"
+
+	"github.com/pkg/errors"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/talon-one/go-httphandler"
+)
+
+func TestGraphQLEncoder(t *testing.T) {
+	var loggedStatusCode int
+	h := httphandler.New(&httphandler.Options{
+		LogFunc: func(_ *http.Request, _, _, _ error, statusCode int, _ string) {
+			loggedStatusCode = statusCode
+		},
+	})
+	require.NoError(t, h.SetEncoder(httphandler.GraphQLResponseContentType, httphandler.GraphQLEncoder()))
+	require.NoError(t, h.SetRequestUUIDFunc(func() string {
+		return "0123456789"
+	}))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/unauthorized", h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		return &httphandler.HandlerError{
+			StatusCode:  http.StatusUnauthorized,
+			PublicError: errors.New("missing token"),
+		}
+	}))
+	mux.HandleFunc("/graphql-error", h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		return &httphandler.HandlerError{
+			StatusCode: http.StatusBadRequest,
+			PublicError: &httphandler.GraphQLError{
+				Message:    "unable to parse query",
+				Code:       "GRAPHQL_PARSE_FAILED",
+				Extensions: map[string]interface{}{"line": 1},
+			},
+		}
+	}))
+	mux.HandleFunc("/ok", h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		return &httphandler.HandlerError{
+			StatusCode:  http.StatusOK,
+			PublicError: errors.New("no data"),
+		}
+	}))
+
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	accept := "application/graphql-response+json, application/json;q=0.9"
+
+	t.Run("transport error", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(hit.JoinURL(s.URL, "unauthorized")),
+			hit.Send().Headers("Accept").Add(accept),
+			hit.Expect().Status().Equal(http.StatusUnauthorized),
+			hit.Expect().Headers("Content-Type").Equal("application/graphql-response+json"),
+			hit.Expect().Body().JSON().Equal(map[string]interface{}{
+				"errors": []interface{}{
+					map[string]interface{}{
+						"message": "missing token",
+						"extensions": map[string]interface{}{
+							"code":        "UNAUTHENTICATED",
+							"requestUUID": "0123456789",
+						},
+					},
+				},
+			}),
+		)
+	})
+
+	t.Run("graphql error", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(hit.JoinURL(s.URL, "graphql-error")),
+			hit.Send().Headers("Accept").Add(accept),
+			hit.Expect().Status().Equal(http.StatusBadRequest),
+			hit.Expect().Body().JSON().JQ(".errors[0].message").Equal("unable to parse query"),
+			hit.Expect().Body().JSON().JQ(".errors[0].extensions").Equal(map[string]interface{}{
+				"code":        "GRAPHQL_PARSE_FAILED",
+				"line":        1,
+				"requestUUID": "0123456789",
+			}),
+		)
+	})
+
+	t.Run("status code without data", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(hit.JoinURL(s.URL, "ok")),
+			hit.Send().Headers("Accept").Add(accept),
+			hit.Expect().Status().Equal(http.StatusInternalServerError),
+			hit.Expect().Body().JSON().JQ(".errors[0].extensions.code").Equal("INTERNAL_SERVER_ERROR"),
+		)
+		require.Equal(t, http.StatusInternalServerError, loggedStatusCode)
+		hit.Test(t,
+			hit.Get(hit.JoinURL(s.URL, "ok")),
+			hit.Send().Headers("Accept").Add("application/json"),
+			hit.Expect().Status().Equal(http.StatusOK),
+		)
+		require.Equal(t, http.StatusOK, loggedStatusCode)
+	})
+}
+
+func TestGraphQLEncoderIsOptIn(t *testing.T) {
+	s := httptest.NewServer(httphandler.New(nil).HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		return &httphandler.HandlerError{
+			StatusCode:  http.StatusNotFound,
+			PublicError: errors.New("user not found"),
+		}
+	}))
+	defer s.Close()
+
+	hit.Test(t,
+		hit.Get(s.URL),
+		hit.Send().Headers("Accept").Add("application/json;q=0, */*"),
+		hit.Expect().Status().Equal(http.StatusNotFound),
+		hit.Expect().Headers("Content-Type").NotEqual(httphandler.GraphQLResponseContentType),
+	)
+}
@@ -3,7 +3,6 @@ package httphandler
 import (
 	"context"
 	"fmt"
-	"mime"
 	"net/http"
 	"strings"
 
@@ -24,7 +23,8 @@ func New(options *Options) *Handler {
 	if options.LogFunc == nil {
 		options.LogFunc = defaultLogFunc()
 	}
-	if options.Encoders == nil {
+	useDefaultEncoders := options.Encoders == nil
+	if useDefaultEncoders {
 		options.Encoders = defaultEncoders()
 	} else {
 		_ = options.SetEncoders(options.Encoders)
@@ -38,10 +38,44 @@ func New(options *Options) *Handler {
 	if options.CustomPanicHandler == nil {
 		options.CustomPanicHandler = defaultCustomPanicHandler()
 	}
+	if options.FormatAliases == nil {
+		options.FormatAliases = defaultFormatAliases()
+	}
+	if options.LanguageFunc == nil {
+		options.LanguageFunc = defaultLanguageFunc()
+	}
+	if options.NotAcceptableEncoderFunc == nil {
+		options.NotAcceptableEncoderFunc = defaultNotAcceptableEncoder()
+	}
+	if options.ValueEncoders == nil {
+		options.ValueEncoders = defaultValueEncoders()
+	}
+	if options.Decoders == nil {
+		options.Decoders = defaultDecoders()
+	}
+	if options.ErrorMappers == nil {
+		options.ErrorMappers = DefaultErrorMappers()
+	}
+	if options.ProblemDetails {
+		options.addProblemEncoders()
+	}
+	if options.registry != nil && options.registry.options != options {
+		// the Options have been copied (e.g. from DefaultOptions), they must not share the registry
+		options.registry = options.registry.clone(options)
+	}
+	// create the registry now, it must not be created lazily while requests are served
+	registry := options.encoderRegistry()
+	if useDefaultEncoders {
+		_ = registry.Register(defaultTextEncoder())
+	}
+	registry.update()
 	return &Handler{options: options}
 }
 
 // HandlerError represents the error that should be returned from the handler func in case of error.
+// It implements error and unwraps to its InternalError and PublicError, so it can be returned (and wrapped) by layers
+// that do not know about http: a HandlerError anywhere in the chain of the error returned to HandleErrorFunc, or of the
+// InternalError of a HandlerError without StatusCode and PublicError, describes the response.
 type HandlerError struct {
 	// StatusCode is the http status code to send to the client.
 	// If not specified HandleFunc will use http.StatusInternalServerError.
@@ -56,6 +90,9 @@ type HandlerError struct {
 	// ContentType specifies the Content-Type of this error. If not specified HandleFunc will use the clients Accept
 	// header. If specified the clients Accept header will be ignored.
 	ContentType string
+	// ContentLanguage specifies the language of the PublicError (e.g. "en"), it will be send to the client in the
+	// Content-Language header. If not specified no Content-Language header will be send.
+	ContentLanguage string
 }
 
 // WireError represents the error that will be send "over the wire" to the client.
@@ -68,6 +105,18 @@ type WireError struct {
 	RequestUUID string
 }
 
+// NotAcceptableError is the error that will be send to the client if the strict negotiation mode is enabled and
+// none of the media types in the clients Accept header is available.
+type NotAcceptableError struct {
+	// AvailableMediaTypes is the list of media types that can be requested.
+	AvailableMediaTypes []string
+}
+
+func (e *NotAcceptableError) Error() string {
+	return "none of the accepted media types is available, available media types are: " +
+		strings.Join(e.AvailableMediaTypes, ", ")
+}
+
 // PanicHandler is the type for custom functions for handling panics.
 type PanicHandler func(context.Context, *HandlerError)
 
@@ -86,6 +135,8 @@ type ServeHTTP interface {
 // In case the provided handler function returns an error, HandleFunc will construct a response based on the error and
 // the Accept header of the client.
 // If the HandlerError specifies a ContentType value the clients Accept header will be ignored.
+// If the request specifies a format using one of the Options.FormatSources, this format takes precedence over the
+// clients Accept header (but not over the ContentType of the HandlerError).
 // If the provided handler function returns no error no action will be taken, this means that the specified handler func
 // is required to send the http headers, status code and body.
 //
@@ -146,6 +197,89 @@ func (h *Handler) SetCustomPanicHandler(f PanicHandler) {
 	h.options.SetCustomPanicHandler(f)
 }
 
+// SetStrictNegotiation enables or disables the strict negotiation mode.
+// In strict negotiation mode an unsatisfiable Accept header results in a 406 Not Acceptable response.
+func (h *Handler) SetStrictNegotiation(strict bool) {
+	h.options.SetStrictNegotiation(strict)
+}
+
+// SetNotAcceptableEncoder sets the encoder that will be used to send the 406 Not Acceptable response in strict
+// negotiation mode.
+func (h *Handler) SetNotAcceptableEncoder(contentType string, encoder EncodeFunc) error {
+	return h.options.SetNotAcceptableEncoder(contentType, encoder)
+}
+
+// SetFormatSources sets the sources that are consulted (in order) for a format override before the clients Accept
+// header is used.
+func (h *Handler) SetFormatSources(sources ...FormatSource) {
+	h.options.SetFormatSources(sources...)
+}
+
+// SetFormatAlias sets the content type for a format alias (e.g. "json" for "application/json").
+func (h *Handler) SetFormatAlias(alias, contentType string) error {
+	return h.options.SetFormatAlias(alias, contentType)
+}
+
+// SetCatalog sets the Catalog that is used to localize a LocalizedError in the PublicError of a HandlerError.
+func (h *Handler) SetCatalog(catalog *Catalog) {
+	h.options.SetCatalog(catalog)
+}
+
+// SetLanguageFunc sets the function that returns the languages the client prefers.
+// It can be used to override the language detection (e.g. to use a cookie or a user setting).
+func (h *Handler) SetLanguageFunc(languageFunc LanguageFunc) error {
+	return h.options.SetLanguageFunc(languageFunc)
+}
+
+// SetProblemDetails enables or disables problem details (RFC 9457) for clients that negotiated a generic content type
+// (e.g. "application/json").
+func (h *Handler) SetProblemDetails(enabled bool) {
+	h.options.SetProblemDetails(enabled)
+}
+
+// SetYAMLEncoder sets the encoder for all YAML media types (see YAMLContentTypes).
+func (h *Handler) SetYAMLEncoder(encoder EncodeFunc) error {
+	return h.options.SetYAMLEncoder(encoder)
+}
+
+// SetValueEncoder sets the encoder that Respond uses to encode values for the content type.
+func (h *Handler) SetValueEncoder(contentType string, encoder ValueEncodeFunc) error {
+	return h.options.SetValueEncoder(contentType, encoder)
+}
+
+// SetDecoders sets the decoders of the specified map of content type and DecodeFunc in the Decoders, decoders for
+// other content types are kept.
+// They will be used to lookup the decoder for the Content-Type of the request.
+func (h *Handler) SetDecoders(decoders map[string]DecodeFunc) error {
+	return h.options.SetDecoders(decoders)
+}
+
+// SetDecoder sets one specific decoder in the Decoders map.
+func (h *Handler) SetDecoder(contentType string, decoder DecodeFunc) error {
+	return h.options.SetDecoder(contentType, decoder)
+}
+
+// SetMaxBodySize sets the maximum size in bytes of request bodies that are decoded by Decode, a negative size disables
+// the limit.
+func (h *Handler) SetMaxBodySize(size int64) {
+	h.options.SetMaxBodySize(size)
+}
+
+// SetErrorMappers sets the ErrorMappers that convert the errors of handlers wrapped with HandleErrorFunc.
+func (h *Handler) SetErrorMappers(mappers ...ErrorMapper) error {
+	return h.options.SetErrorMappers(mappers...)
+}
+
+// AddErrorMapper adds a mapper that takes precedence over the existing ErrorMappers.
+func (h *Handler) AddErrorMapper(mapper ErrorMapper) error {
+	return h.options.AddErrorMapper(mapper)
+}
+
+// Encoders returns the EncoderRegistry of the Handler.
+func (h *Handler) Encoders() *EncoderRegistry {
+	return h.options.encoderRegistry()
+}
+
 // callNextHandler calls the next specified handler func.
 func (h *Handler) callNextHandler(handler HandlerFunc, w http.ResponseWriter, r *http.Request) {
 	safeWriter := newSafeResponseWriter(w)
@@ -157,12 +291,25 @@ func (h *Handler) callNextHandler(handler HandlerFunc, w http.ResponseWriter, r
 		return
 	}
 
+	err = resolveHandlerError(err)
 	if err.StatusCode == 0 {
 		err.StatusCode = http.StatusInternalServerError
 	}
 	if err.PublicError == nil {
 		err.PublicError = errors.New("unknown error")
 	}
+
+	// we have written already
+	if safeWriter.Written() {
+		h.logHandlerError(r, err, requestUUID)
+		return
+	}
+
+	h.sendError(err, requestUUID, safeWriter, requestWithContext, true)
+}
+
+// logHandlerError logs the HandlerError that was returned by the handler.
+func (h *Handler) logHandlerError(r *http.Request, err *HandlerError, requestUUID string) {
 	h.options.LogFunc(r,
 		errors.New("handler error"),
 		err.InternalError,
@@ -170,13 +317,6 @@ func (h *Handler) callNextHandler(handler HandlerFunc, w http.ResponseWriter, r
 		err.StatusCode,
 		requestUUID,
 	)
-
-	// we have written already
-	if safeWriter.Written() {
-		return
-	}
-
-	h.sendError(err, requestUUID, safeWriter, requestWithContext)
 }
 
 func safeHandlerCall(h HandlerFunc, w http.ResponseWriter, r *http.Request, ph PanicHandler) (err *HandlerError) {
@@ -201,7 +341,16 @@ func safeHandlerCall(h HandlerFunc, w http.ResponseWriter, r *http.Request, ph P
 	return err
 }
 
-func (h *Handler) sendError(err *HandlerError, requestUUID string, w http.ResponseWriter, r *http.Request) {
+// sendError sends the HandlerError to the client. If logError is set the HandlerError is logged once the status code
+// that is sent is known (it might be changed by the negotiated content type, see statusCodeRules).
+func (h *Handler) sendError(err *HandlerError, requestUUID string, w http.ResponseWriter, r *http.Request, logError bool) {
+	// vary holds the request headers that have been used to select the representation
+	var vary []string
+
+	if h.localize(err, r) {
+		vary = append(vary, "Accept-Language")
+	}
+
 	errorToSend := &WireError{
 		StatusCode:  err.StatusCode,
 		Error:       err.PublicError,
@@ -210,30 +359,70 @@ func (h *Handler) sendError(err *HandlerError, requestUUID string, w http.Respon
 
 	var f EncodeFunc
 
-	if err.ContentType == "" {
-		f, err.ContentType = getPreferredContentType(h.options, r)
-	} else {
+	switch {
+	case err.ContentType != "":
 		err.ContentType = strings.ToLower(err.ContentType)
-		f = h.options.Encoders[err.ContentType]
+		f = lookupEncoder(h.options.Encoders, err.ContentType)
+	case len(h.options.FormatSources) > 0:
+		var formatVary []string
+		f, err.ContentType, formatVary = getFormatOverride(h.options, r)
+		vary = append(vary, formatVary...)
+	}
+
+	if err.ContentType == "" {
+		var acceptable bool
+		vary = append(vary, "Accept")
+		f, err.ContentType, acceptable = getPreferredContentType(h.options, r)
+		if !acceptable && h.options.StrictNegotiation {
+			if logError {
+				h.logHandlerError(r, err, requestUUID)
+			}
+			addVary(w.Header(), vary...)
+			h.sendNotAcceptable(err, requestUUID, w, r)
+			return
+		}
 	}
 
+	isFallback := false
 	if f == nil || err.ContentType == "" {
 		// use fallback
 		f, err.ContentType = h.options.FallbackEncoderFunc()
 		err.ContentType = strings.ToLower(err.ContentType)
+		isFallback = true
 	}
 
-	w.Header().Set("Content-Type", err.ContentType)
-	w.WriteHeader(err.StatusCode)
-	if encodeErr := f(w, r, errorToSend); encodeErr != nil {
-		h.options.LogFunc(r,
-			errors.Wrapf(encodeErr, "unable to encode %q", err.ContentType),
-			err.InternalError,
-			err.PublicError,
-			err.StatusCode,
-			requestUUID,
-		)
+	if h.options.ProblemDetails {
+		contentType := err.ContentType
+		f, err.ContentType = getProblemEncoder(h.options, f, err.ContentType)
+		isFallback = isFallback && contentType == err.ContentType
 	}
+
+	err.StatusCode = applyStatusCodeRule(err.ContentType, err.StatusCode)
+	errorToSend.StatusCode = err.StatusCode
+	if logError {
+		h.logHandlerError(r, err, requestUUID)
+	}
+
+	addVary(w.Header(), vary...)
+	if err.ContentLanguage != "" {
+		w.Header().Set("Content-Language", err.ContentLanguage)
+	}
+	h.encode(w, r, err, errorToSend, f, err.ContentType, isFallback)
+}
+
+// sendNotAcceptable sends a 406 Not Acceptable response listing the media types that are available.
+// The err is the original HandlerError, it is only used for logging.
+func (h *Handler) sendNotAcceptable(err *HandlerError, requestUUID string, w http.ResponseWriter, r *http.Request) {
+	errorToSend := &WireError{
+		StatusCode: http.StatusNotAcceptable,
+		Error: &NotAcceptableError{
+			AvailableMediaTypes: encoderContentTypes(h.options.Encoders),
+		},
+		RequestUUID: requestUUID,
+	}
+
+	f, contentType := h.options.NotAcceptableEncoderFunc()
+	h.encode(w, r, err, errorToSend, f, contentType, false)
 }
 
 type httpHandler struct {
@@ -245,24 +434,6 @@ func (h *httpHandler) ServeHTTP(writer http.ResponseWriter, request *http.Reques
 	h.handler.callNextHandler(h.serveHTTP.ServeHTTP, writer, request)
 }
 
-func getPreferredContentType(options *Options, r *http.Request) (enocder EncodeFunc, contentType string) {
-	// if the request has a Accept header use this header to determinate the output format
-	if accept := r.Header.Values("Accept"); len(accept) != 0 {
-		for _, s := range accept {
-			mediaType, _, err := mime.ParseMediaType(s)
-			if err != nil {
-				continue
-			}
-			ct := strings.ToLower(mediaType)
-			f, ok := options.Encoders[ct]
-			if ok {
-				return f, ct
-			}
-		}
-	}
-	return nil, ""
-}
-
 // DefaultHandler is the default instance that can be used out of the box.
 // It uses the default settings.
 var DefaultHandler = New(nil)
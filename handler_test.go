@@ -2,6 +2,7 @@ package httphandler_test
 
 import (
 	"context"
+	"encoding/xml"
 	"fmt"
 	"io"
 	"net/http"
@@ -230,7 +231,9 @@ func TestFaultyEncoder(t *testing.T) {
 	hit.Test(t,
 		hit.Get(s.URL),
 		hit.Expect().Status().Equal(http.StatusInternalServerError),
-		hit.Expect().Headers("Content-Type").Equal("application/octet-stream"),
+		// the fallback encoder failed, so a minimal body is sent
+		hit.Expect().Headers("Content-Type").Equal("text/plain; charset=utf-8"),
+		hit.Expect().Body().String().Equal("500 Internal Server Error (RequestUUID: 0123456789)\n"),
 	)
 
 	require.Len(t, errorLog, 2)
@@ -238,6 +241,67 @@ func TestFaultyEncoder(t *testing.T) {
 	require.EqualError(t, errorLog[1], `unable to encode "application/octet-stream": encoder error`)
 }
 
+func TestBufferedEncoding(t *testing.T) {
+	var errorLog []error
+	h := httphandler.New(&httphandler.Options{
+		LogFunc: func(_ *http.Request, handlerError, internalError, publicError error, statusCode int, requestUUID string) {
+			errorLog = append(errorLog, handlerError)
+		},
+		Encoders: map[string]httphandler.EncodeFunc{
+			"application/xml": func(w http.ResponseWriter, r *http.Request, _ *httphandler.WireError) error {
+				w.Header().Set("X-Partial", "true")
+				_, _ = io.WriteString(w, "<partial")
+				return errors.New("encoder error")
+			},
+			"text/plain": func(w http.ResponseWriter, r *http.Request, e *httphandler.WireError) error {
+				w.Header().Set("X-Encoder", "text")
+				_, err := io.WriteString(w, e.Error.Error())
+				return err
+			},
+		},
+		FallbackEncoderFunc: func() (httphandler.EncodeFunc, string) {
+			return stringEncoder("fallback"), "application/json"
+		},
+	})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		return &httphandler.HandlerError{
+			StatusCode:  http.StatusConflict,
+			PublicError: errors.New("conflict"),
+		}
+	}))
+
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	t.Run("failing encoder", func(t *testing.T) {
+		errorLog = nil
+		hit.Test(t,
+			hit.Get(s.URL),
+			hit.Send().Headers("Accept").Add("application/xml"),
+			hit.Expect().Status().Equal(http.StatusConflict),
+			hit.Expect().Headers("Content-Type").Equal("application/json"),
+			hit.Expect().Headers("Content-Length").Equal("8"),
+			hit.Expect().Headers("X-Partial").Empty(),
+			hit.Expect().Body().String().Equal("fallback"),
+		)
+		require.Len(t, errorLog, 2)
+		require.EqualError(t, errorLog[1], `unable to encode "application/xml": encoder error`)
+	})
+
+	t.Run("headers of the encoder", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(s.URL),
+			hit.Send().Headers("Accept").Add("text/plain"),
+			hit.Expect().Status().Equal(http.StatusConflict),
+			hit.Expect().Headers("Content-Type").Equal("text/plain"),
+			hit.Expect().Headers("Content-Length").Equal("8"),
+			hit.Expect().Headers("X-Encoder").Equal("text"),
+			hit.Expect().Body().String().Equal("conflict"),
+		)
+	})
+}
+
 func TestDoubleWrite(t *testing.T) {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", httphandler.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
@@ -428,6 +492,17 @@ func TestSetInvalidOptions(t *testing.T) {
 	}), "content-type cannot be empty")
 	require.EqualError(t, h.SetFallbackEncoder("text/html", nil), "encoder cannot be nil")
 	require.EqualError(t, h.SetRequestUUIDFunc(nil), "requestUUIDFunc cannot be nil")
+	require.EqualError(t, h.SetNotAcceptableEncoder("", func(_ http.ResponseWriter, _ *http.Request, _ *httphandler.WireError) error {
+		return nil
+	}), "content-type cannot be empty")
+	require.EqualError(t, h.SetNotAcceptableEncoder("text/html", nil), "encoder cannot be nil")
+	require.EqualError(t, h.SetValueEncoder("", httphandler.DefaultJSONValueEncoder()), "content-type cannot be empty")
+	require.EqualError(t, h.SetValueEncoder("application/json", nil), "encoder cannot be nil")
+	require.EqualError(t, h.SetDecoders(nil), "decoders cannot be nil")
+	require.EqualError(t, h.SetDecoder("", func(_ *http.Request, _ interface{}) error {
+		return nil
+	}), "content-type cannot be empty")
+	require.EqualError(t, h.SetDecoder("text/csv", nil), "decoder cannot be nil")
 }
 
 func TestDefaultEncoders(t *testing.T) {
@@ -439,15 +514,19 @@ func TestDefaultEncoders(t *testing.T) {
 	s := httptest.NewServer(mux)
 	defer s.Close()
 
-	defaultEncoders := httphandler.DefaultOptions().Encoders
-
-	for contentType := range defaultEncoders {
-		hit.Test(t,
-			hit.Get(s.URL),
-			hit.Expect().Status().Equal(http.StatusInternalServerError),
-			hit.Send().Headers("Accept").Add(contentType),
-			hit.Expect().Headers("Content-Type").Equal(contentType),
-		)
+	for _, info := range httphandler.DefaultHandler.Encoders().List() {
+		for _, contentType := range info.MediaTypes {
+			expected := contentType
+			if info.Charset != "" {
+				expected += "; charset=" + info.Charset
+			}
+			hit.Test(t,
+				hit.Get(s.URL),
+				hit.Expect().Status().Equal(http.StatusInternalServerError),
+				hit.Send().Headers("Accept").Add(contentType),
+				hit.Expect().Headers("Content-Type").Equal(expected),
+			)
+		}
 	}
 }
 
@@ -592,3 +671,67 @@ func TestHandler(t *testing.T) {
 		hit.Expect().Body().JSON().JQ(".Error").Equal("unknown error"),
 	)
 }
+
+type xmlError struct {
+	Title   string `xml:"title"`
+	Details string `xml:"details,attr"`
+}
+
+func (e xmlError) Error() string {
+	return e.Title
+}
+
+func (e xmlError) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
+	type plain xmlError
+	return enc.EncodeElement(plain(e), start)
+}
+
+func TestXMLEncoder(t *testing.T) {
+	encode := func(encoder httphandler.EncodeFunc, err error) string {
+		w := httptest.NewRecorder()
+		require.NoError(t, encoder(w, nil, &httphandler.WireError{
+			StatusCode:  http.StatusBadRequest,
+			Error:       err,
+			RequestUUID: "0123456789",
+		}))
+		return w.Body.String()
+	}
+
+	t.Run("plain error", func(t *testing.T) {
+		require.Equal(t, xml.Header+
+			`<WireError><StatusCode>400</StatusCode><Error>a &lt; b &amp; c</Error><RequestUUID>0123456789</RequestUUID></WireError>`,
+			encode(httphandler.DefaultXMLEncoder(), errors.New("a < b & c")),
+		)
+	})
+
+	t.Run("custom error without marshaler", func(t *testing.T) {
+		require.Equal(t, xml.Header+
+			"<WireError><StatusCode>400</StatusCode><Error>error: title=`Some Error&#39; details=`Not implemented&#39;</Error>"+
+			"<RequestUUID>0123456789</RequestUUID></WireError>",
+			encode(httphandler.DefaultXMLEncoder(), extendedError{Title: "Some Error", Details: "Not implemented"}),
+		)
+	})
+
+	t.Run("xml marshaler", func(t *testing.T) {
+		require.Equal(t, xml.Header+
+			`<WireError><StatusCode>400</StatusCode><Error details="&lt;details&gt;"><title>Some Error</title></Error>`+
+			`<RequestUUID>0123456789</RequestUUID></WireError>`,
+			encode(httphandler.DefaultXMLEncoder(), xmlError{Title: "Some Error", Details: "<details>"}),
+		)
+	})
+
+	t.Run("text marshaler", func(t *testing.T) {
+		require.Equal(t, xml.Header+
+			`<WireError><StatusCode>400</StatusCode><Error>not_found</Error><RequestUUID>0123456789</RequestUUID></WireError>`,
+			encode(httphandler.DefaultXMLEncoder(), &httphandler.LocalizedError{Key: "not_found"}),
+		)
+	})
+
+	t.Run("namespace", func(t *testing.T) {
+		require.Equal(t, xml.Header+
+			`<WireError xmlns="urn:example"><StatusCode>400</StatusCode><Error>error</Error>`+
+			`<RequestUUID>0123456789</RequestUUID></WireError>`,
+			encode(httphandler.XMLEncoder("urn:example"), errors.New("error")),
+		)
+	})
+}
@@ -0,0 +1,173 @@
+package httphandler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// Error returns the status code and the message of the PublicError (e.g. "404 Not Found: user not found").
+// The InternalError is left out, so the message can be shown to clients; it can still be inspected with errors.Is and
+// errors.As (see Unwrap).
+func (e *HandlerError) Error() string {
+	statusCode := e.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusInternalServerError
+	}
+	msg := strconv.Itoa(statusCode) + " " + statusText(statusCode)
+	if e.PublicError != nil {
+		msg += ": " + e.PublicError.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the InternalError and the PublicError, so both can be inspected with errors.Is and errors.As.
+func (e *HandlerError) Unwrap() []error {
+	errs := make([]error, 0, 2) //nolint:gomnd // InternalError and PublicError
+	if e.InternalError != nil {
+		errs = append(errs, e.InternalError)
+	}
+	if e.PublicError != nil {
+		errs = append(errs, e.PublicError)
+	}
+	return errs
+}
+
+// WithStatusCode sets the StatusCode of the HandlerError.
+func (e *HandlerError) WithStatusCode(statusCode int) *HandlerError {
+	e.StatusCode = statusCode
+	return e
+}
+
+// WithPublicError sets the PublicError of the HandlerError.
+func (e *HandlerError) WithPublicError(err error) *HandlerError {
+	e.PublicError = err
+	return e
+}
+
+// WithInternalError sets the InternalError of the HandlerError.
+//
+// Example:
+//
+//	return NotFound("user not found").WithInternalError(err)
+func (e *HandlerError) WithInternalError(err error) *HandlerError {
+	e.InternalError = err
+	return e
+}
+
+// WithContentType sets the ContentType of the HandlerError.
+func (e *HandlerError) WithContentType(contentType string) *HandlerError {
+	e.ContentType = contentType
+	return e
+}
+
+// WithContentLanguage sets the ContentLanguage of the HandlerError.
+func (e *HandlerError) WithContentLanguage(language string) *HandlerError {
+	e.ContentLanguage = language
+	return e
+}
+
+// NewHandlerError returns a HandlerError with the status code and message as PublicError.
+// If message is empty the status text (e.g. "Not Found") will be used.
+func NewHandlerError(statusCode int, message string) *HandlerError {
+	if message == "" {
+		message = statusText(statusCode)
+	}
+	return &HandlerError{
+		StatusCode:  statusCode,
+		PublicError: errors.New(message),
+	}
+}
+
+// BadRequest returns a 400 Bad Request HandlerError, see NewHandlerError.
+func BadRequest(message string) *HandlerError {
+	return NewHandlerError(http.StatusBadRequest, message)
+}
+
+// Unauthorized returns a 401 Unauthorized HandlerError, see NewHandlerError.
+func Unauthorized(message string) *HandlerError {
+	return NewHandlerError(http.StatusUnauthorized, message)
+}
+
+// Forbidden returns a 403 Forbidden HandlerError, see NewHandlerError.
+func Forbidden(message string) *HandlerError {
+	return NewHandlerError(http.StatusForbidden, message)
+}
+
+// NotFound returns a 404 Not Found HandlerError, see NewHandlerError.
+func NotFound(message string) *HandlerError {
+	return NewHandlerError(http.StatusNotFound, message)
+}
+
+// MethodNotAllowed returns a 405 Method Not Allowed HandlerError, see NewHandlerError.
+func MethodNotAllowed(message string) *HandlerError {
+	return NewHandlerError(http.StatusMethodNotAllowed, message)
+}
+
+// Conflict returns a 409 Conflict HandlerError, see NewHandlerError.
+func Conflict(message string) *HandlerError {
+	return NewHandlerError(http.StatusConflict, message)
+}
+
+// Gone returns a 410 Gone HandlerError, see NewHandlerError.
+func Gone(message string) *HandlerError {
+	return NewHandlerError(http.StatusGone, message)
+}
+
+// UnprocessableEntity returns a 422 Unprocessable Entity HandlerError, see NewHandlerError.
+func UnprocessableEntity(message string) *HandlerError {
+	return NewHandlerError(http.StatusUnprocessableEntity, message)
+}
+
+// TooManyRequests returns a 429 Too Many Requests HandlerError, see NewHandlerError.
+func TooManyRequests(message string) *HandlerError {
+	return NewHandlerError(http.StatusTooManyRequests, message)
+}
+
+// InternalServerError returns a 500 Internal Server Error HandlerError, see NewHandlerError.
+func InternalServerError(message string) *HandlerError {
+	return NewHandlerError(http.StatusInternalServerError, message)
+}
+
+// ServiceUnavailable returns a 503 Service Unavailable HandlerError, see NewHandlerError.
+func ServiceUnavailable(message string) *HandlerError {
+	return NewHandlerError(http.StatusServiceUnavailable, message)
+}
+
+// findHandlerError returns the HandlerError in the chain of err (see errors.As), or nil.
+// If the HandlerError is wrapped a copy is returned that has err as InternalError, so the context that was added by
+// wrapping is logged.
+func findHandlerError(err error) *HandlerError {
+	var handlerError *HandlerError
+	if !errors.As(err, &handlerError) || handlerError == nil {
+		return nil
+	}
+	if handlerError == err {
+		return handlerError
+	}
+	resolved := *handlerError
+	resolved.InternalError = err
+	return &resolved
+}
+
+// resolveHandlerError returns a copy of the HandlerError that describes the response, so HandlerErrors can be shared
+// (e.g. as package level variables) although the handler modifies them while sending.
+// If err does not specify a StatusCode or a PublicError but a HandlerError is wrapped in its InternalError, the wrapped
+// HandlerError is used.
+func resolveHandlerError(err *HandlerError) *HandlerError {
+	resolved := *err
+	if err.StatusCode != 0 || err.PublicError != nil || err.InternalError == nil {
+		return &resolved
+	}
+	if inner := findHandlerError(err.InternalError); inner != nil {
+		resolved = *inner
+		if err.ContentType != "" {
+			resolved.ContentType = err.ContentType
+		}
+		if err.ContentLanguage != "" {
+			resolved.ContentLanguage = err.ContentLanguage
+		}
+	}
+	return &resolved
+}
@@ -0,0 +1,126 @@
+package httphandler_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Eun/go-hit"
+
+	"github.com/pkg/errors"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/talon-one/go-httphandler"
+)
+
+var errHandlerErrorNoRows = errors.New("no rows in result set")
+
+func TestHandlerErrorIsError(t *testing.T) {
+	publicError := errors.New("user not found")
+	err := httphandler.NotFound("").
+		WithPublicError(publicError).
+		WithInternalError(errHandlerErrorNoRows).
+		WithContentType("application/xml").
+		WithContentLanguage("en")
+
+	require.Equal(t, "404 Not Found: user not found", err.Error())
+	require.Equal(t, "application/xml", err.ContentType)
+	require.Equal(t, "en", err.ContentLanguage)
+
+	wrapped := fmt.Errorf("load user: %w", err)
+	require.ErrorIs(t, wrapped, errHandlerErrorNoRows)
+	require.ErrorIs(t, wrapped, publicError)
+	var handlerError *httphandler.HandlerError
+	require.ErrorAs(t, wrapped, &handlerError)
+	require.Equal(t, http.StatusNotFound, handlerError.StatusCode)
+
+	require.Equal(t, "500 Internal Server Error", (&httphandler.HandlerError{}).Error())
+	require.Equal(t, "Conflict", httphandler.Conflict("").PublicError.Error())
+	require.Equal(t, "499 Client Closed Request: Client Closed Request", httphandler.NewHandlerError(499, "").Error())
+	require.Equal(t, http.StatusTeapot, httphandler.BadRequest("tea").WithStatusCode(http.StatusTeapot).StatusCode)
+
+	constructors := map[int]func(string) *httphandler.HandlerError{
+		http.StatusBadRequest:          httphandler.BadRequest,
+		http.StatusUnauthorized:        httphandler.Unauthorized,
+		http.StatusForbidden:           httphandler.Forbidden,
+		http.StatusNotFound:            httphandler.NotFound,
+		http.StatusMethodNotAllowed:    httphandler.MethodNotAllowed,
+		http.StatusConflict:            httphandler.Conflict,
+		http.StatusGone:                httphandler.Gone,
+		http.StatusUnprocessableEntity: httphandler.UnprocessableEntity,
+		http.StatusTooManyRequests:     httphandler.TooManyRequests,
+		http.StatusInternalServerError: httphandler.InternalServerError,
+		http.StatusServiceUnavailable:  httphandler.ServiceUnavailable,
+	}
+	for statusCode, constructor := range constructors {
+		err := constructor("message")
+		require.Equal(t, statusCode, err.StatusCode)
+		require.Equal(t, "message", err.PublicError.Error())
+	}
+}
+
+var errHandlerErrorUserNotFound = httphandler.NotFound("user not found")
+
+func TestWrappedHandlerError(t *testing.T) {
+	var loggedErrors []error
+	h := httphandler.New(&httphandler.Options{
+		LogFunc: func(_ *http.Request, _, internalError, _ error, _ int, _ string) {
+			loggedErrors = append(loggedErrors, internalError)
+		},
+	})
+	require.NoError(t, h.SetRequestUUIDFunc(func() string {
+		return "0123456789"
+	}))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/error-func", h.HandleErrorFunc(func(w http.ResponseWriter, r *http.Request) error {
+		return errors.Wrap(errHandlerErrorUserNotFound, "service")
+	}))
+	mux.HandleFunc("/internal", h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		return &httphandler.HandlerError{
+			InternalError: fmt.Errorf("service: %w", httphandler.Conflict("user exists")),
+			ContentType:   "text/plain",
+		}
+	}))
+	mux.HandleFunc("/shared", h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		return errHandlerErrorUserNotFound
+	}))
+
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	t.Run("handle error func", func(t *testing.T) {
+		loggedErrors = nil
+		hit.Test(t,
+			hit.Get(hit.JoinURL(s.URL, "error-func")),
+			hit.Expect().Status().Equal(http.StatusNotFound),
+			hit.Expect().Body().JSON().Equal(map[string]interface{}{
+				"StatusCode":  http.StatusNotFound,
+				"Error":       "user not found",
+				"RequestUUID": "0123456789",
+			}),
+		)
+		require.Len(t, loggedErrors, 1)
+		require.EqualError(t, loggedErrors[0], "service: 404 Not Found: user not found")
+	})
+
+	t.Run("internal error chain", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(hit.JoinURL(s.URL, "internal")),
+			hit.Expect().Status().Equal(http.StatusConflict),
+			hit.Expect().Headers("Content-Type").Equal("text/plain; charset=utf-8"),
+			hit.Expect().Body().String().Equal("409 Conflict: user exists (RequestUUID: 0123456789)\n"),
+		)
+	})
+
+	t.Run("shared handler error", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(hit.JoinURL(s.URL, "shared")),
+			hit.Send().Headers("Accept").Add("text/html"),
+			hit.Expect().Status().Equal(http.StatusNotFound),
+			hit.Expect().Headers("Content-Type").Equal("text/html"),
+		)
+		require.Empty(t, errHandlerErrorUserNotFound.ContentType)
+	})
+}
@@ -0,0 +1,137 @@
+package httphandler
+
+import (
+	"bytes"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrorTemplateData is the data that is passed to the templates of the HTMLEncoder and the layouts of the TextEncoder.
+type ErrorTemplateData struct {
+	// StatusCode is the http status code that was sent to the client.
+	StatusCode int
+	// StatusText is the text of the StatusCode (e.g. "Not Found").
+	StatusText string
+	// Message is the message of the public error.
+	Message string
+	// RequestUUID is the request uuid of the request.
+	RequestUUID string
+	// Error is the public error.
+	Error error
+}
+
+func newErrorTemplateData(e *WireError) *ErrorTemplateData {
+	data := &ErrorTemplateData{
+		StatusCode:  e.StatusCode,
+		StatusText:  statusText(e.StatusCode),
+		RequestUUID: e.RequestUUID,
+		Error:       e.Error,
+	}
+	if e.Error != nil {
+		data.Message = e.Error.Error()
+	}
+	return data
+}
+
+// DefaultHTMLTemplateName is the name of the template that is used if there is no template for the status code.
+const DefaultHTMLTemplateName = "error.html"
+
+const defaultHTMLTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.StatusCode}} {{.StatusText}}</title>
+</head>
+<body>
+<h1>{{.StatusCode}} {{.StatusText}}</h1>
+{{if .Message}}<p>{{.Message}}</p>{{end}}
+<hr>
+<p>RequestUUID: <code>{{.RequestUUID}}</code></p>
+</body>
+</html>
+`
+
+var defaultHTMLTemplates = template.Must(template.New(DefaultHTMLTemplateName).Parse(defaultHTMLTemplate))
+
+// HTMLEncoder implements an HTML encoder that renders the WireError using the templates of t, the templates get an
+// ErrorTemplateData as data. Since html/template is used all values are escaped.
+// The template is selected by the status code, e.g. for 404 the templates "404.html", "4xx.html" and
+// DefaultHTMLTemplateName are tried in this order.
+func HTMLEncoder(t *template.Template) EncodeFunc {
+	return func(w http.ResponseWriter, r *http.Request, e *WireError) error {
+		tmpl := lookupHTMLTemplate(t, e.StatusCode)
+		if tmpl == nil {
+			return errors.Errorf("no template for status code %d", e.StatusCode)
+		}
+
+		// render into a buffer first, so a failing template does not result in a partial page
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, newErrorTemplateData(e)); err != nil {
+			return errors.Wrapf(err, "unable to execute template %q", tmpl.Name())
+		}
+		_, err := buf.WriteTo(w)
+		return err
+	}
+}
+
+// lookupHTMLTemplate returns the template for the status code.
+func lookupHTMLTemplate(t *template.Template, statusCode int) *template.Template {
+	code := strconv.Itoa(statusCode)
+	names := []string{code + ".html", DefaultHTMLTemplateName}
+	if len(code) == len("000") {
+		names = []string{code + ".html", code[:1] + "xx.html", DefaultHTMLTemplateName}
+	}
+	for _, name := range names {
+		if tmpl := t.Lookup(name); tmpl != nil {
+			return tmpl
+		}
+	}
+	return nil
+}
+
+// ParseHTMLTemplatesFS parses the templates that match the patterns in fsys, the templates are named after their base
+// name (e.g. "404.html"). The result can be used with HTMLEncoder.
+// The templates can use the asset function to inline files of fsys, e.g. {{asset "static/style.css"}}. Assets with
+// the extension ".css" are inlined as CSS, ".js" as JavaScript and ".svg" as HTML; every other asset is escaped.
+//
+// Example:
+//
+//	//go:embed errors
+//	var errorPages embed.FS
+//
+//	t, err := ParseHTMLTemplatesFS(errorPages, "errors/*.html")
+//	if err != nil {
+//	    return err
+//	}
+//	handler.SetEncoder("text/html", HTMLEncoder(t))
+func ParseHTMLTemplatesFS(fsys fs.FS, patterns ...string) (*template.Template, error) {
+	t := template.New("").Funcs(template.FuncMap{
+		"asset": func(name string) (interface{}, error) {
+			buf, err := fs.ReadFile(fsys, name)
+			if err != nil {
+				return nil, err
+			}
+			switch strings.ToLower(path.Ext(name)) {
+			case ".css":
+				return template.CSS(buf), nil //nolint:gosec // assets are part of the (trusted) file system
+			case ".js":
+				return template.JS(buf), nil //nolint:gosec // assets are part of the (trusted) file system
+			case ".svg":
+				return template.HTML(buf), nil //nolint:gosec // assets are part of the (trusted) file system
+			default:
+				return string(buf), nil
+			}
+		},
+	})
+	t, err := t.ParseFS(fsys, patterns...)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse templates")
+	}
+	return t, nil
+}
@@ -0,0 +1,88 @@
+package httphandler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/pkg/errors"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/talon-one/go-httphandler"
+)
+
+func TestDefaultHTMLEncoder(t *testing.T) {
+	w := httptest.NewRecorder()
+	require.NoError(t, httphandler.DefaultHTMLEncoder()(w, nil, &httphandler.WireError{
+		StatusCode:  http.StatusBadRequest,
+		Error:       errors.New(`<script>alert("xss")</script>`),
+		RequestUUID: "0123456789",
+	}))
+	body := w.Body.String()
+	require.Contains(t, body, "<title>400 Bad Request</title>")
+	require.Contains(t, body, "<p>&lt;script&gt;alert(&#34;xss&#34;)&lt;/script&gt;</p>")
+	require.Contains(t, body, "<code>0123456789</code>")
+	require.NotContains(t, body, "<script>")
+}
+
+func TestHTMLEncoder(t *testing.T) {
+	fsys := fstest.MapFS{
+		"errors/404.html":    {Data: []byte(`not found: {{.Message}}`)},
+		"errors/5xx.html":    {Data: []byte(`<style>{{asset "static/style.css"}}</style>{{.StatusCode}} {{.StatusText}}`)},
+		"errors/error.html":  {Data: []byte(`error {{.StatusCode}} {{.RequestUUID}}`)},
+		"failing/error.html": {Data: []byte(`partial {{.Error.Unknown}}`)},
+		"static/style.css":   {Data: []byte(`body { color: red; }`)},
+	}
+	tmpl, err := httphandler.ParseHTMLTemplatesFS(fsys, "errors/*.html")
+	require.NoError(t, err)
+	encoder := httphandler.HTMLEncoder(tmpl)
+
+	encode := func(statusCode int) string {
+		w := httptest.NewRecorder()
+		require.NoError(t, encoder(w, nil, &httphandler.WireError{
+			StatusCode:  statusCode,
+			Error:       errors.New("<user>"),
+			RequestUUID: "0123456789",
+		}))
+		return w.Body.String()
+	}
+
+	t.Run("status code template", func(t *testing.T) {
+		require.Equal(t, "not found: &lt;user&gt;", encode(http.StatusNotFound))
+	})
+
+	t.Run("status class template", func(t *testing.T) {
+		require.Equal(t, "<style>body { color: red; }</style>503 Service Unavailable", encode(http.StatusServiceUnavailable))
+	})
+
+	t.Run("default template", func(t *testing.T) {
+		require.Equal(t, "error 400 0123456789", encode(http.StatusBadRequest))
+	})
+
+	t.Run("missing template", func(t *testing.T) {
+		tmpl, err := httphandler.ParseHTMLTemplatesFS(fsys, "errors/404.html")
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		require.EqualError(t, httphandler.HTMLEncoder(tmpl)(w, nil, &httphandler.WireError{
+			StatusCode: http.StatusBadRequest,
+		}), "no template for status code 400")
+	})
+
+	t.Run("failing template", func(t *testing.T) {
+		tmpl, err := httphandler.ParseHTMLTemplatesFS(fsys, "failing/*.html")
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		require.Error(t, httphandler.HTMLEncoder(tmpl)(w, nil, &httphandler.WireError{
+			StatusCode: http.StatusBadRequest,
+			Error:      errors.New("error"),
+		}))
+		require.Empty(t, w.Body.String())
+	})
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		_, err := httphandler.ParseHTMLTemplatesFS(fsys, "unknown/*.html")
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,197 @@
+package httphandler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// NamingStrategy converts the (PascalCase) field names of the WireError (e.g. "RequestUUID") into the names used on
+// the wire.
+type NamingStrategy func(name string) string
+
+// PascalCase keeps the field names as they are (e.g. "RequestUUID").
+func PascalCase(name string) string {
+	return name
+}
+
+// CamelCase converts the field names to camelCase (e.g. "requestUUID").
+func CamelCase(name string) string {
+	words := splitWords(name)
+	if len(words) == 0 {
+		return name
+	}
+	words[0] = strings.ToLower(words[0])
+	return strings.Join(words, "")
+}
+
+// SnakeCase converts the field names to snake_case (e.g. "request_uuid").
+func SnakeCase(name string) string {
+	return strings.ToLower(strings.Join(splitWords(name), "_"))
+}
+
+// KebabCase converts the field names to kebab-case (e.g. "request-uuid").
+func KebabCase(name string) string {
+	return strings.ToLower(strings.Join(splitWords(name), "-"))
+}
+
+// splitWords splits a PascalCase name into its words, acronyms are kept together (e.g. "RequestUUID" results in
+// "Request" and "UUID").
+func splitWords(name string) []string {
+	runes := []rune(name)
+	var words []string
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		if !unicode.IsUpper(runes[i]) {
+			continue
+		}
+		// a new word starts at an upper case letter that follows a lower case letter, or at the last upper case
+		// letter of an acronym that is followed by a lower case letter
+		if !unicode.IsUpper(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	if start < len(runes) {
+		words = append(words, string(runes[start:]))
+	}
+	return words
+}
+
+// JSONEncoderOptions configure the JSON encoder that is returned by JSONEncoder.
+// The zero value results in the output of DefaultJSONEncoder.
+type JSONEncoderOptions struct {
+	// NamingStrategy converts the field names, it is not applied to field names that are set explicitly.
+	// If NamingStrategy is nil PascalCase will be used.
+	NamingStrategy NamingStrategy
+	// StatusCodeField is the name of the StatusCode field.
+	StatusCodeField string
+	// ErrorField is the name of the Error field.
+	ErrorField string
+	// RequestUUIDField is the name of the RequestUUID field.
+	RequestUUIDField string
+	// OmitStatusCode leaves out the StatusCode.
+	OmitStatusCode bool
+	// OmitRequestUUID leaves out the RequestUUID.
+	OmitRequestUUID bool
+	// Envelope wraps the fields in an object with this name (e.g. "error" results in {"error":{...}}).
+	Envelope string
+	// Indent enables pretty printing, every nesting level is indented with Indent (e.g. "  ").
+	Indent string
+	// DisableHTMLEscaping disables the escaping of <, > and & in strings.
+	DisableHTMLEscaping bool
+}
+
+// fieldName returns the name of the field, explicit names have precedence over the naming strategy.
+func (o *JSONEncoderOptions) fieldName(explicit, name string) string {
+	if explicit != "" {
+		return explicit
+	}
+	if o.NamingStrategy == nil {
+		return name
+	}
+	return o.NamingStrategy(name)
+}
+
+// marshal marshals v using the HTML escaping setting of the options.
+func (o *JSONEncoderOptions) marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(!o.DisableHTMLEscaping)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// JSONEncoder implements a JSON encoder that renders the WireError as configured by options.
+// Like DefaultJSONEncoder it marshals the Error first (so a custom PublicError can implement json.Marshaler) and uses
+// the Error() function if the marshaled error is empty.
+//
+// Example:
+//
+//	// {"error":{"error":"user not found","request_id":"..."}}
+//	encoder := JSONEncoder(JSONEncoderOptions{
+//	    NamingStrategy:   SnakeCase,
+//	    RequestUUIDField: "request_id",
+//	    OmitStatusCode:   true,
+//	    Envelope:         "error",
+//	})
+func JSONEncoder(options JSONEncoderOptions) EncodeFunc {
+	return func(w http.ResponseWriter, r *http.Request, e *WireError) error {
+		// marshal the Error before everything else
+		errorValue, err := options.marshal(e.Error)
+		if err != nil {
+			return errors.Wrap(err, "unable to encode error")
+		}
+
+		// if the error message is empty use the Error() function
+		if e.Error != nil && (len(errorValue) == 0 || string(errorValue) == "{}" || string(errorValue) == "null") {
+			if errorValue, err = options.marshal(e.Error.Error()); err != nil {
+				return errors.Wrap(err, "unable to encode error")
+			}
+		}
+
+		type field struct {
+			name  string
+			value interface{}
+		}
+		fields := make([]field, 0, 3) //nolint:gomnd // StatusCode, Error and RequestUUID
+		if !options.OmitStatusCode {
+			fields = append(fields, field{options.fieldName(options.StatusCodeField, "StatusCode"), e.StatusCode})
+		}
+		fields = append(fields, field{options.fieldName(options.ErrorField, "Error"), json.RawMessage(errorValue)})
+		if !options.OmitRequestUUID {
+			fields = append(fields, field{options.fieldName(options.RequestUUIDField, "RequestUUID"), e.RequestUUID})
+		}
+
+		// build the object by hand to keep the order of the fields
+		var buf bytes.Buffer
+		if options.Envelope != "" {
+			name, err := options.marshal(options.Envelope)
+			if err != nil {
+				return err
+			}
+			buf.WriteByte('{')
+			buf.Write(name)
+			buf.WriteByte(':')
+		}
+		buf.WriteByte('{')
+		for i, f := range fields {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			name, err := options.marshal(f.name)
+			if err != nil {
+				return err
+			}
+			value, err := options.marshal(f.value)
+			if err != nil {
+				return err
+			}
+			buf.Write(name)
+			buf.WriteByte(':')
+			buf.Write(value)
+		}
+		buf.WriteByte('}')
+		if options.Envelope != "" {
+			buf.WriteByte('}')
+		}
+
+		if options.Indent != "" {
+			var indented bytes.Buffer
+			if err := json.Indent(&indented, buf.Bytes(), "", options.Indent); err != nil {
+				return err
+			}
+			buf = indented
+		}
+		buf.WriteByte('\n')
+
+		_, err = buf.WriteTo(w)
+		return err
+	}
+}
@@ -0,0 +1,94 @@
+package httphandler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/talon-one/go-httphandler"
+)
+
+type jsonError struct {
+	Title string `json:"title"`
+}
+
+func (e jsonError) Error() string {
+	return e.Title
+}
+
+func TestNamingStrategies(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy httphandler.NamingStrategy
+		expected []string
+	}{
+		{"PascalCase", httphandler.PascalCase, []string{"StatusCode", "Error", "RequestUUID", "HTTPStatus"}},
+		{"CamelCase", httphandler.CamelCase, []string{"statusCode", "error", "requestUUID", "httpStatus"}},
+		{"SnakeCase", httphandler.SnakeCase, []string{"status_code", "error", "request_uuid", "http_status"}},
+		{"KebabCase", httphandler.KebabCase, []string{"status-code", "error", "request-uuid", "http-status"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for i, name := range []string{"StatusCode", "Error", "RequestUUID", "HTTPStatus"} {
+				require.Equal(t, test.expected[i], test.strategy(name))
+			}
+		})
+	}
+}
+
+func TestJSONEncoder(t *testing.T) {
+	encode := func(options httphandler.JSONEncoderOptions, err error) string {
+		w := httptest.NewRecorder()
+		require.NoError(t, httphandler.JSONEncoder(options)(w, nil, &httphandler.WireError{
+			StatusCode:  http.StatusBadRequest,
+			Error:       err,
+			RequestUUID: "0123456789",
+		}))
+		return w.Body.String()
+	}
+
+	t.Run("default", func(t *testing.T) {
+		require.Equal(t, `{"StatusCode":400,"Error":"\u003cinvalid\u003e","RequestUUID":"0123456789"}`+"\n",
+			encode(httphandler.JSONEncoderOptions{}, errors.New("<invalid>")))
+	})
+
+	t.Run("html escaping", func(t *testing.T) {
+		require.Equal(t, `{"StatusCode":400,"Error":"<invalid>","RequestUUID":"0123456789"}`+"\n",
+			encode(httphandler.JSONEncoderOptions{DisableHTMLEscaping: true}, errors.New("<invalid>")))
+	})
+
+	t.Run("json.Marshaler", func(t *testing.T) {
+		require.Equal(t, `{"StatusCode":400,"Error":{"title":"invalid"},"RequestUUID":"0123456789"}`+"\n",
+			encode(httphandler.JSONEncoderOptions{}, jsonError{Title: "invalid"}))
+	})
+
+	t.Run("naming strategy and field names", func(t *testing.T) {
+		require.Equal(t, `{"status_code":400,"message":"invalid","request_uuid":"0123456789"}`+"\n",
+			encode(httphandler.JSONEncoderOptions{
+				NamingStrategy: httphandler.SnakeCase,
+				ErrorField:     "message",
+			}, errors.New("invalid")))
+	})
+
+	t.Run("omit fields and envelope", func(t *testing.T) {
+		require.Equal(t, `{"error":{"message":"invalid"}}`+"\n",
+			encode(httphandler.JSONEncoderOptions{
+				ErrorField:      "message",
+				OmitStatusCode:  true,
+				OmitRequestUUID: true,
+				Envelope:        "error",
+			}, errors.New("invalid")))
+	})
+
+	t.Run("pretty printing", func(t *testing.T) {
+		require.Equal(t, "{\n  \"statusCode\": 400,\n  \"error\": {\n    \"title\": \"invalid\"\n  },\n  \"requestUUID\": \"0123456789\"\n}\n",
+			encode(httphandler.JSONEncoderOptions{
+				NamingStrategy: httphandler.CamelCase,
+				Indent:         "  ",
+			}, jsonError{Title: "invalid"}))
+	})
+}
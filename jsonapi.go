@@ -0,0 +1,131 @@
+package httphandler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// JSONAPIContentType is the media type of JSON:API documents.
+const JSONAPIContentType = "application/vnd.api+json"
+
+// JSONAPIError is a PublicError that carries the members of a JSON:API error object
+// (https://jsonapi.org/format/#error-objects). Use JSONAPIErrors to send multiple errors with one HandlerError.
+//
+// Example:
+//
+//	return &HandlerError{
+//	    StatusCode: http.StatusUnprocessableEntity,
+//	    PublicError: JSONAPIErrors{
+//	        {Code: "invalid", Title: "Invalid Attribute", Source: &JSONAPISource{Pointer: "/data/attributes/email"}},
+//	        {Code: "missing", Title: "Missing Attribute", Source: &JSONAPISource{Pointer: "/data/attributes/name"}},
+//	    },
+//	}
+type JSONAPIError struct {
+	// ID is a unique identifier for this occurrence of the problem. If not specified the RequestUUID will be used,
+	// followed by the index of the error (e.g. "<RequestUUID>-1") if the document contains multiple errors.
+	ID string `json:"id,omitempty"`
+	// Status is the http status code as string. If not specified the status code of the HandlerError will be used.
+	Status string `json:"status,omitempty"`
+	// Code is an application specific error code.
+	Code string `json:"code,omitempty"`
+	// Title is a short, human-readable summary of the problem.
+	Title string `json:"title,omitempty"`
+	// Detail is a human-readable explanation specific to this occurrence of the problem.
+	Detail string `json:"detail,omitempty"`
+	// Source references the part of the request that caused the problem.
+	Source *JSONAPISource `json:"source,omitempty"`
+	// Meta contains non-standard meta-information about the error.
+	Meta map[string]interface{} `json:"meta,omitempty"`
+}
+
+func (e *JSONAPIError) Error() string {
+	if e.Detail != "" {
+		return e.Detail
+	}
+	if e.Title != "" {
+		return e.Title
+	}
+	return e.Code
+}
+
+// JSONAPISource references the part of the request that caused the problem of a JSONAPIError.
+type JSONAPISource struct {
+	// Pointer is a JSON Pointer (RFC 6901) to the value in the request document (e.g. "/data/attributes/title").
+	Pointer string `json:"pointer,omitempty"`
+	// Parameter is the name of the query parameter that caused the error.
+	Parameter string `json:"parameter,omitempty"`
+	// Header is the name of the request header that caused the error.
+	Header string `json:"header,omitempty"`
+}
+
+// JSONAPIErrors is a PublicError that contains multiple JSON:API error objects.
+type JSONAPIErrors []*JSONAPIError
+
+func (e JSONAPIErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// jsonAPIErrors returns the JSON:API error objects for the WireError.
+// If the Error of the WireError is neither JSONAPIErrors nor a *JSONAPIError an error object is created that uses the
+// status text as title and the error message as detail.
+func jsonAPIErrors(e *WireError) []JSONAPIError {
+	var list JSONAPIErrors
+	var single *JSONAPIError
+	switch {
+	case errors.As(e.Error, &list):
+	case errors.As(e.Error, &single):
+		list = JSONAPIErrors{single}
+	default:
+		err := JSONAPIError{Title: statusText(e.StatusCode)}
+		if e.Error != nil {
+			err.Detail = e.Error.Error()
+		}
+		list = JSONAPIErrors{&err}
+	}
+
+	result := make([]JSONAPIError, 0, len(list))
+	for _, err := range list {
+		if err == nil {
+			continue
+		}
+		obj := *err
+		if obj.ID == "" && e.RequestUUID != "" {
+			// ids must be unique per occurrence, so documents with multiple errors get an index suffix
+			obj.ID = e.RequestUUID
+			if len(list) > 1 {
+				obj.ID += "-" + strconv.Itoa(len(result))
+			}
+		}
+		if obj.Status == "" {
+			obj.Status = strconv.Itoa(e.StatusCode)
+		}
+		result = append(result, obj)
+	}
+	return result
+}
+
+// JSONAPIEncoder implements an encoder for JSON:API error documents (e.g. {"errors":[{"id":"...","status":"404"}]}).
+// It is not part of the default Encoders, register it for JSONAPIContentType if the handlers serve JSON:API clients.
+// The RequestUUID (with an index suffix in documents with multiple errors) is used as id and the status code as status
+// of error objects that do not specify them.
+//
+// Example:
+//
+//	handler.SetEncoder(JSONAPIContentType, JSONAPIEncoder())
+func JSONAPIEncoder() EncodeFunc {
+	return func(w http.ResponseWriter, r *http.Request, e *WireError) error {
+		return json.NewEncoder(w).Encode(struct {
+			Errors []JSONAPIError `json:"errors"`
+		}{
+			Errors: jsonAPIErrors(e),
+		})
+	}
+}
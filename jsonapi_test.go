@@ -0,0 +1,111 @@
+package httphandler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Eun/go-hit"
+
+	"github.com/pkg/errors"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/talon-one/go-httphandler"
+)
+
+func TestJSONAPIEncoder(t *testing.T) {
+	h := httphandler.New(nil)
+	require.NoError(t, h.SetEncoder(httphandler.JSONAPIContentType, httphandler.JSONAPIEncoder()))
+	require.NoError(t, h.SetRequestUUIDFunc(func() string {
+		return "0123456789"
+	}))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/errors", h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		return &httphandler.HandlerError{
+			StatusCode: http.StatusUnprocessableEntity,
+			PublicError: httphandler.JSONAPIErrors{
+				{
+					Code:   "invalid",
+					Title:  "Invalid Attribute",
+					Detail: "email is not valid",
+					Source: &httphandler.JSONAPISource{Pointer: "/data/attributes/email"},
+					Meta:   map[string]interface{}{"pattern": "*@*"},
+				},
+				{
+					ID:     "custom",
+					Status: "400",
+					Title:  "Invalid Query Parameter",
+					Source: &httphandler.JSONAPISource{Parameter: "sort"},
+				},
+			},
+		}
+	}))
+	mux.HandleFunc("/error", h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		return &httphandler.HandlerError{
+			StatusCode:  http.StatusNotFound,
+			PublicError: errors.New("user not found"),
+		}
+	}))
+
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	t.Run("multiple errors", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(hit.JoinURL(s.URL, "errors")),
+			hit.Send().Headers("Accept").Add("application/vnd.api+json"),
+			hit.Expect().Status().Equal(http.StatusUnprocessableEntity),
+			hit.Expect().Headers("Content-Type").Equal("application/vnd.api+json"),
+			hit.Expect().Body().JSON().Equal(map[string]interface{}{
+				"errors": []interface{}{
+					map[string]interface{}{
+						"id":     "0123456789-0",
+						"status": "422",
+						"code":   "invalid",
+						"title":  "Invalid Attribute",
+						"detail": "email is not valid",
+						"source": map[string]interface{}{"pointer": "/data/attributes/email"},
+						"meta":   map[string]interface{}{"pattern": "*@*"},
+					},
+					map[string]interface{}{
+						"id":     "custom",
+						"status": "400",
+						"title":  "Invalid Query Parameter",
+						"source": map[string]interface{}{"parameter": "sort"},
+					},
+				},
+			}),
+		)
+	})
+
+	t.Run("plain error", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(hit.JoinURL(s.URL, "error")),
+			hit.Send().Headers("Accept").Add("application/vnd.api+json"),
+			hit.Expect().Status().Equal(http.StatusNotFound),
+			hit.Expect().Body().JSON().Equal(map[string]interface{}{
+				"errors": []interface{}{
+					map[string]interface{}{
+						"id":     "0123456789",
+						"status": "404",
+						"title":  "Not Found",
+						"detail": "user not found",
+					},
+				},
+			}),
+		)
+	})
+
+	t.Run("other encoders", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(hit.JoinURL(s.URL, "errors")),
+			hit.Send().Headers("Accept").Add("text/plain"),
+			hit.Expect().Body().String().Contains("email is not valid; Invalid Query Parameter"),
+		)
+	})
+}
+
+func TestJSONAPIEncoderIsOptIn(t *testing.T) {
+	require.NotContains(t, httphandler.DefaultOptions().Encoders, httphandler.JSONAPIContentType)
+}
@@ -0,0 +1,136 @@
+package httphandler
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// LocalizedError is a PublicError whose message is looked up in the Catalog of the Handler, in the language the
+// client prefers. If the Handler has no Catalog, or the Catalog has no message for the Key, the Key is used as
+// message.
+//
+// Example:
+//
+//	return &HandlerError{
+//	    StatusCode: http.StatusNotFound,
+//	    PublicError: &LocalizedError{
+//	        Key:    "not_found",
+//	        Params: map[string]interface{}{"Resource": "user"},
+//	    },
+//	}
+type LocalizedError struct {
+	// Key is the key of the message in the Catalog.
+	Key string
+	// Params are the parameters that are available in the message template.
+	Params map[string]interface{}
+	// Count selects the plural form of the message, it is available as .Count in the message template.
+	Count int
+	// Message is the localized message, it is set by the Handler before the error is encoded.
+	Message string
+	// Language is the language of the Message, it is set by the Handler before the error is encoded.
+	Language string
+}
+
+// Error returns the localized Message, or the Key if the error has not been localized yet.
+func (e *LocalizedError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return e.Key
+}
+
+// MarshalText implements encoding.TextMarshaler, so encoders render the error as its message.
+func (e *LocalizedError) MarshalText() ([]byte, error) {
+	return []byte(e.Error()), nil
+}
+
+// LanguageFunc returns the languages the client prefers, ordered by preference.
+type LanguageFunc func(r *http.Request) []string
+
+func defaultLanguageFunc() LanguageFunc {
+	return func(r *http.Request) []string {
+		return parseAcceptLanguage(r.Header.Values("Accept-Language"))
+	}
+}
+
+// parseAcceptLanguage returns the language tags of the Accept-Language header values ordered by their quality.
+// Languages with q=0 and the wildcard "*" are left out.
+func parseAcceptLanguage(values []string) []string {
+	type languageRange struct {
+		tag     string
+		quality float64
+	}
+	var ranges []languageRange
+	for _, value := range values {
+		for _, s := range splitHeaderList(value) {
+			parts := strings.Split(s, ";")
+			tag := strings.TrimSpace(parts[0])
+			if tag == "" || tag == "*" {
+				continue
+			}
+			quality := 1.0
+			for _, param := range parts[1:] {
+				param = strings.TrimSpace(param)
+				if !strings.HasPrefix(param, "q=") {
+					continue
+				}
+				q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+				if err != nil || q < 0 || q > 1 {
+					quality = 0
+					continue
+				}
+				quality = q
+			}
+			if quality <= 0 {
+				continue
+			}
+			ranges = append(ranges, languageRange{tag: tag, quality: quality})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].quality > ranges[j].quality
+	})
+	languages := make([]string, len(ranges))
+	for i := range ranges {
+		languages[i] = ranges[i].tag
+	}
+	return languages
+}
+
+// localize resolves a LocalizedError in the PublicError of err using the Catalog.
+// The PublicError will be replaced with a localized copy of the LocalizedError, so errors that are shared between
+// requests are not modified. It reports whether the error has been localized.
+func (h *Handler) localize(err *HandlerError, r *http.Request) bool {
+	if h.options.Catalog == nil || err.PublicError == nil {
+		return false
+	}
+	var localizedError *LocalizedError
+	if !errors.As(err.PublicError, &localizedError) {
+		return false
+	}
+
+	message, language, localizeErr := h.options.Catalog.Localize(h.options.LanguageFunc(r), localizedError)
+	if localizeErr != nil {
+		h.options.LogFunc(r,
+			errors.Wrap(localizeErr, "unable to localize error"),
+			err.InternalError,
+			err.PublicError,
+			err.StatusCode,
+			GetRequestUUID(r),
+		)
+		return false
+	}
+
+	localized := *localizedError
+	localized.Message = message
+	localized.Language = language
+	err.PublicError = &localized
+	if err.ContentLanguage == "" {
+		err.ContentLanguage = language
+	}
+	return true
+}
@@ -0,0 +1,130 @@
+package httphandler
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// ErrorMapper converts an error into a HandlerError, it returns nil if it does not handle the error.
+// The ErrorMappers of the Options are consulted by HandleErrorFunc to convert the errors returned by the handler.
+type ErrorMapper func(err error) *HandlerError
+
+// MapErrorIs returns an ErrorMapper that maps errors that match target (using errors.Is) to a HandlerError with the
+// status code and public error.
+//
+// Example:
+//
+//	handler.AddErrorMapper(MapErrorIs(sql.ErrNoRows, http.StatusNotFound, errors.New("not found")))
+func MapErrorIs(target error, statusCode int, publicError error) ErrorMapper {
+	return func(err error) *HandlerError {
+		if !errors.Is(err, target) {
+			return nil
+		}
+		return &HandlerError{
+			StatusCode:    statusCode,
+			PublicError:   publicError,
+			InternalError: err,
+		}
+	}
+}
+
+// MapErrorAs returns an ErrorMapper that maps errors of type T (using errors.As) to the HandlerError that is returned
+// by f, f can return nil to skip the error.
+//
+// Example:
+//
+//	handler.AddErrorMapper(MapErrorAs(func(err *ValidationError) *HandlerError {
+//	    return &HandlerError{StatusCode: http.StatusUnprocessableEntity, PublicError: err}
+//	}))
+func MapErrorAs[T error](f func(err T) *HandlerError) ErrorMapper {
+	return func(err error) *HandlerError {
+		var target T
+		if !errors.As(err, &target) {
+			return nil
+		}
+		return f(target)
+	}
+}
+
+// DefaultErrorMappers returns the built-in ErrorMappers:
+// context.DeadlineExceeded results in 504 Gateway Timeout, context.Canceled in 499 Client Closed Request,
+// os.ErrNotExist in 404 Not Found and *http.MaxBytesError in 413 Request Entity Too Large.
+func DefaultErrorMappers() []ErrorMapper {
+	return []ErrorMapper{
+		MapErrorIs(context.DeadlineExceeded, http.StatusGatewayTimeout, errors.New("request timed out")),
+		MapErrorIs(context.Canceled, statusClientClosedRequest, errors.New("request canceled")),
+		MapErrorIs(os.ErrNotExist, http.StatusNotFound, errors.New("not found")),
+		MapErrorAs(func(err *http.MaxBytesError) *HandlerError {
+			return &HandlerError{
+				StatusCode:  http.StatusRequestEntityTooLarge,
+				PublicError: errors.Errorf("request body exceeds the limit of %d bytes", err.Limit),
+			}
+		}),
+	}
+}
+
+// mapError converts the error into a HandlerError using the ErrorMappers, the first mapper that handles the error
+// wins. A HandlerError in the chain of the error is used as it is, errors that are not handled by any mapper result in
+// a 500 Internal Server Error.
+func (h *Handler) mapError(err error) *HandlerError {
+	if handlerError := findHandlerError(err); handlerError != nil {
+		return handlerError
+	}
+	for _, mapper := range h.options.ErrorMappers {
+		if handlerError := mapper(err); handlerError != nil {
+			// mappers may return shared HandlerErrors, so the InternalError is set on a copy
+			mapped := *handlerError
+			if mapped.InternalError == nil {
+				mapped.InternalError = err
+			}
+			return &mapped
+		}
+	}
+	return &HandlerError{
+		StatusCode:    http.StatusInternalServerError,
+		InternalError: err,
+	}
+}
+
+// isNilError reports whether the error is nil, including typed nil pointers (e.g. a nil *MyError returned as error).
+func isNilError(err error) bool {
+	if err == nil {
+		return true
+	}
+	v := reflect.ValueOf(err)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
+
+// HandleErrorFunc wraps a handler that returns a plain error, the error is converted into a HandlerError using the
+// ErrorMappers of the Options and sent like in HandleFunc.
+// A nil pointer that is returned as error (e.g. a nil *MyError) is treated as no error.
+//
+// Example:
+//
+//	http.HandleFunc("/file", handler.HandleErrorFunc(func(w http.ResponseWriter, r *http.Request) error {
+//	    f, err := os.Open("data.json") // a missing file results in 404 Not Found
+//	    if err != nil {
+//	        return err
+//	    }
+//	    defer f.Close()
+//	    _, err = io.Copy(w, f)
+//	    return err
+//	}))
+func (h *Handler) HandleErrorFunc(handler func(w http.ResponseWriter, r *http.Request) error) http.HandlerFunc {
+	return h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *HandlerError {
+		if err := handler(w, r); !isNilError(err) {
+			return h.mapError(err)
+		}
+		return nil
+	})
+}
+
+// HandleErrorFunc wraps a handler that returns a plain error using the DefaultHandler.
+// See also Handler.HandleErrorFunc.
+func HandleErrorFunc(handler func(w http.ResponseWriter, r *http.Request) error) http.HandlerFunc {
+	return DefaultHandler.HandleErrorFunc(handler)
+}
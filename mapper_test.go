@@ -0,0 +1,169 @@
+package httphandler_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Eun/go-hit"
+
+	"github.com/pkg/errors"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/talon-one/go-httphandler"
+)
+
+type mapperError struct {
+	Field string
+}
+
+func (e *mapperError) Error() string {
+	return "invalid field " + e.Field
+}
+
+var errMapperConflict = errors.New("conflict")
+
+func TestHandleErrorFunc(t *testing.T) {
+	h := httphandler.New(nil)
+	require.NoError(t, h.SetRequestUUIDFunc(func() string {
+		return "0123456789"
+	}))
+	require.NoError(t, h.SetEncoder("application/problem+json", httphandler.ProblemJSONEncoder()))
+	require.NoError(t, h.AddErrorMapper(httphandler.MapErrorIs(errMapperConflict, http.StatusConflict, errors.New("already exists"))))
+	require.NoError(t, h.AddErrorMapper(httphandler.MapErrorAs(func(err *mapperError) *httphandler.HandlerError {
+		return &httphandler.HandlerError{StatusCode: http.StatusUnprocessableEntity, PublicError: errors.New(err.Error())}
+	})))
+	require.Error(t, h.AddErrorMapper(nil))
+
+	var errorToReturn error
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", h.HandleErrorFunc(func(w http.ResponseWriter, r *http.Request) error {
+		if errorToReturn == nil {
+			_, _ = io.WriteString(w, "ok")
+		}
+		return errorToReturn
+	}))
+	mux.HandleFunc("/too-large", h.HandleErrorFunc(func(w http.ResponseWriter, r *http.Request) error {
+		_, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 4))
+		return err
+	}))
+	mux.HandleFunc("/typed-nil", h.HandleErrorFunc(func(w http.ResponseWriter, r *http.Request) error {
+		var err *mapperError
+		_, _ = io.WriteString(w, "ok")
+		return err
+	}))
+
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	tests := []struct {
+		name       string
+		err        error
+		statusCode int
+		message    string
+	}{
+		{"deadline exceeded", errors.Wrap(context.DeadlineExceeded, "query"), http.StatusGatewayTimeout, "request timed out"},
+		{"canceled", context.Canceled, 499, "request canceled"},
+		{"not exist", &os.PathError{Op: "open", Path: "data.json", Err: os.ErrNotExist}, http.StatusNotFound, "not found"},
+		{"registered is", errors.Wrap(errMapperConflict, "insert"), http.StatusConflict, "already exists"},
+		{"registered as", errors.Wrap(&mapperError{Field: "name"}, "validate"), http.StatusUnprocessableEntity, "invalid field name"},
+		{"unmapped", errors.New("boom"), http.StatusInternalServerError, "unknown error"},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			errorToReturn = test.err
+			hit.Test(t,
+				hit.Get(s.URL),
+				hit.Expect().Status().Equal(int64(test.statusCode)),
+				hit.Expect().Body().JSON().Equal(map[string]interface{}{
+					"StatusCode":  test.statusCode,
+					"Error":       test.message,
+					"RequestUUID": "0123456789",
+				}),
+			)
+		})
+	}
+
+	t.Run("status text of canceled requests", func(t *testing.T) {
+		errorToReturn = context.Canceled
+		hit.Test(t,
+			hit.Get(s.URL),
+			hit.Send().Headers("Accept").Add("application/problem+json"),
+			hit.Expect().Status().Equal(499),
+			hit.Expect().Body().JSON().JQ(".title").Equal("Client Closed Request"),
+		)
+	})
+
+	t.Run("no error", func(t *testing.T) {
+		errorToReturn = nil
+		hit.Test(t,
+			hit.Get(s.URL),
+			hit.Expect().Status().Equal(http.StatusOK),
+			hit.Expect().Body().String().Equal("ok"),
+		)
+	})
+
+	t.Run("typed nil", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(hit.JoinURL(s.URL, "typed-nil")),
+			hit.Expect().Status().Equal(http.StatusOK),
+			hit.Expect().Body().String().Equal("ok"),
+		)
+	})
+
+	t.Run("max bytes", func(t *testing.T) {
+		hit.Test(t,
+			hit.Post(hit.JoinURL(s.URL, "too-large")),
+			hit.Send().Body().String(strings.Repeat("a", 16)),
+			hit.Expect().Status().Equal(http.StatusRequestEntityTooLarge),
+			hit.Expect().Body().JSON().JQ(".Error").Equal("request body exceeds the limit of 4 bytes"),
+		)
+	})
+}
+
+func TestSetErrorMappers(t *testing.T) {
+	h := httphandler.New(nil)
+	require.NoError(t, h.SetErrorMappers())
+	s := httptest.NewServer(h.HandleErrorFunc(func(w http.ResponseWriter, r *http.Request) error {
+		return os.ErrNotExist
+	}))
+	defer s.Close()
+
+	hit.Test(t,
+		hit.Get(s.URL),
+		hit.Expect().Status().Equal(http.StatusInternalServerError),
+	)
+}
+
+var errMapperShared = httphandler.Gone("gone")
+
+func TestErrorMapperReturnsSharedHandlerError(t *testing.T) {
+	var loggedErrors []error
+	h := httphandler.New(&httphandler.Options{
+		LogFunc: func(_ *http.Request, _, internalError, _ error, _ int, _ string) {
+			loggedErrors = append(loggedErrors, internalError)
+		},
+	})
+	require.NoError(t, h.SetErrorMappers(func(err error) *httphandler.HandlerError {
+		return errMapperShared
+	}))
+	s := httptest.NewServer(h.HandleErrorFunc(func(w http.ResponseWriter, r *http.Request) error {
+		return errMapperConflict
+	}))
+	defer s.Close()
+
+	hit.Test(t,
+		hit.Get(s.URL),
+		hit.Expect().Status().Equal(http.StatusGone),
+		hit.Expect().Body().JSON().JQ(".Error").Equal("gone"),
+	)
+	require.Len(t, loggedErrors, 1)
+	require.Equal(t, errMapperConflict, loggedErrors[0])
+	require.Nil(t, errMapperShared.InternalError)
+}
@@ -0,0 +1,137 @@
+package httphandler
+
+import (
+	"math"
+	"net/http"
+)
+
+// MsgpackMarshaler is the interface implemented by a PublicError that can marshal itself into MessagePack.
+// It is compatible with the Marshaler interface of github.com/vmihailenco/msgpack.
+type MsgpackMarshaler interface {
+	MarshalMsgpack() ([]byte, error)
+}
+
+// DefaultMsgpackEncoder implements a MessagePack encoder that renders the WireError as map with the same keys
+// DefaultJSONEncoder uses.
+// If the Error implements MsgpackMarshaler its output is used as value for the Error, otherwise the Error is marshaled
+// (using its JSON representation) and the Error() function is used if the marshaled error is empty.
+// The encoder is not part of the default Encoders.
+//
+// Example:
+//
+//	handler.SetEncoder("application/msgpack", DefaultMsgpackEncoder())
+func DefaultMsgpackEncoder() EncodeFunc {
+	return func(w http.ResponseWriter, r *http.Request, e *WireError) error {
+		buf, err := encodeBinaryWireError(&msgpackWriter{}, e, func(err error) ([]byte, bool, error) {
+			m, ok := err.(MsgpackMarshaler)
+			if !ok {
+				return nil, false, nil
+			}
+			buf, err := m.MarshalMsgpack()
+			return buf, true, err
+		})
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(buf)
+		return err
+	}
+}
+
+// msgpackWriter writes MessagePack (https://github.com/msgpack/msgpack/blob/master/spec.md).
+type msgpackWriter struct {
+	buf []byte
+}
+
+func (m *msgpackWriter) writeNil() {
+	m.buf = append(m.buf, 0xc0)
+}
+
+func (m *msgpackWriter) writeBool(v bool) {
+	if v {
+		m.buf = append(m.buf, 0xc3)
+		return
+	}
+	m.buf = append(m.buf, 0xc2)
+}
+
+func (m *msgpackWriter) writeInt(v int64) {
+	switch {
+	case v >= 0:
+		m.writeUint(uint64(v))
+	case v >= -32:
+		m.buf = append(m.buf, byte(v)) // negative fixint
+	case v >= math.MinInt8:
+		m.buf = append(m.buf, 0xd0, byte(v))
+	case v >= math.MinInt16:
+		m.buf = append(m.buf, 0xd1)
+		m.buf = appendUint16(m.buf, uint16(v))
+	case v >= math.MinInt32:
+		m.buf = append(m.buf, 0xd2)
+		m.buf = appendUint32(m.buf, uint32(v))
+	default:
+		m.buf = append(m.buf, 0xd3)
+		m.buf = appendUint64(m.buf, uint64(v))
+	}
+}
+
+func (m *msgpackWriter) writeUint(v uint64) {
+	switch {
+	case v <= math.MaxInt8:
+		m.buf = append(m.buf, byte(v)) // positive fixint
+	case v <= math.MaxUint8:
+		m.buf = append(m.buf, 0xcc, byte(v))
+	case v <= math.MaxUint16:
+		m.buf = append(m.buf, 0xcd)
+		m.buf = appendUint16(m.buf, uint16(v))
+	case v <= math.MaxUint32:
+		m.buf = append(m.buf, 0xce)
+		m.buf = appendUint32(m.buf, uint32(v))
+	default:
+		m.buf = append(m.buf, 0xcf)
+		m.buf = appendUint64(m.buf, v)
+	}
+}
+
+func (m *msgpackWriter) writeFloat(v float64) {
+	m.buf = append(m.buf, 0xcb)
+	m.buf = appendUint64(m.buf, math.Float64bits(v))
+}
+
+func (m *msgpackWriter) writeString(v string) {
+	m.writeHeader(len(v), 0xa0, 31, 0xd9, 0xda, 0xdb) //nolint:gomnd // fixstr holds up to 31 bytes
+	m.buf = append(m.buf, v...)
+}
+
+func (m *msgpackWriter) writeArrayHeader(n int) {
+	m.writeHeader(n, 0x90, 15, 0, 0xdc, 0xdd) //nolint:gomnd // fixarray holds up to 15 elements
+}
+
+func (m *msgpackWriter) writeMapHeader(n int) {
+	m.writeHeader(n, 0x80, 15, 0, 0xde, 0xdf) //nolint:gomnd // fixmap holds up to 15 entries
+}
+
+// writeHeader writes the header of a string, array or map with length n. The fix format is used if n <= fixMax,
+// otherwise the smallest of the 8 (if available), 16 and 32 bit formats.
+func (m *msgpackWriter) writeHeader(n int, fix byte, fixMax int, f8, f16, f32 byte) {
+	switch {
+	case n <= fixMax:
+		m.buf = append(m.buf, fix|byte(n))
+	case f8 != 0 && n <= math.MaxUint8:
+		m.buf = append(m.buf, f8, byte(n))
+	case n <= math.MaxUint16:
+		m.buf = append(m.buf, f16)
+		m.buf = appendUint16(m.buf, uint16(n))
+	default:
+		m.buf = append(m.buf, f32)
+		m.buf = appendUint32(m.buf, uint32(n))
+	}
+}
+
+func (m *msgpackWriter) writeRaw(v []byte) {
+	m.buf = append(m.buf, v...)
+}
+
+func (m *msgpackWriter) bytes() []byte {
+	return m.buf
+}
@@ -0,0 +1,282 @@
+package httphandler
+
+import (
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// specificity levels of a media range, see RFC 7231 section 5.3.2.
+	specificityAny = iota
+	specificityType
+	specificityExact
+)
+
+// acceptRange is a single media range of an Accept header (e.g. "text/*;q=0.8").
+type acceptRange struct {
+	mainType string
+	subType  string
+	quality  float64
+	// index is the position of the range in the Accept header, it is used to keep the clients order on ties.
+	index int
+}
+
+// specificity returns how specific the range is.
+func (a *acceptRange) specificity() int {
+	switch {
+	case a.mainType == "*":
+		return specificityAny
+	case a.subType == "*":
+		return specificityType
+	default:
+		return specificityExact
+	}
+}
+
+// matches reports whether the media range matches the specified media type.
+func (a *acceptRange) matches(mediaType string) bool {
+	mainType, subType := splitMediaType(mediaType)
+	switch a.specificity() {
+	case specificityAny:
+		return true
+	case specificityType:
+		return a.mainType == mainType
+	default:
+		return a.mainType == mainType && a.subType == subType
+	}
+}
+
+// parseAccept parses the specified Accept header values into a list of media ranges.
+// Every value can contain multiple comma separated ranges. Invalid ranges are skipped.
+func parseAccept(values []string) []acceptRange {
+	var ranges []acceptRange
+	for _, value := range values {
+		for _, s := range splitHeaderList(value) {
+			mediaType, params, err := mime.ParseMediaType(s)
+			if err != nil {
+				continue
+			}
+			mainType, subType := splitMediaType(mediaType)
+			if mainType == "" || subType == "" || (mainType == "*" && subType != "*") {
+				continue
+			}
+			quality := 1.0
+			if q, ok := params["q"]; ok {
+				quality, err = strconv.ParseFloat(q, 64)
+				if err != nil || quality < 0 || quality > 1 {
+					continue
+				}
+			}
+			ranges = append(ranges, acceptRange{
+				mainType: mainType,
+				subType:  subType,
+				quality:  quality,
+				index:    len(ranges),
+			})
+		}
+	}
+	return ranges
+}
+
+// splitHeaderList splits a comma separated header value, commas inside quoted strings are ignored.
+func splitHeaderList(value string) []string {
+	var parts []string
+	inQuotes := false
+	escaped := false
+	start := 0
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; {
+		case escaped:
+			escaped = false
+		case c == '\\' && inQuotes:
+			escaped = true
+		case c == '"':
+			inQuotes = !inQuotes
+		case c == ',' && !inQuotes:
+			parts = appendNonEmpty(parts, value[start:i])
+			start = i + 1
+		}
+	}
+	return appendNonEmpty(parts, value[start:])
+}
+
+func appendNonEmpty(parts []string, s string) []string {
+	if s = strings.TrimSpace(s); s != "" {
+		return append(parts, s)
+	}
+	return parts
+}
+
+// splitMediaType splits a media type into its type and subtype, both in lower case.
+func splitMediaType(mediaType string) (mainType, subType string) {
+	mediaType = strings.ToLower(mediaType)
+	i := strings.IndexByte(mediaType, '/')
+	if i < 0 {
+		return mediaType, ""
+	}
+	return mediaType[:i], mediaType[i+1:]
+}
+
+// negotiation is the result of matching one candidate media type against the media ranges.
+type negotiation struct {
+	mediaType   string
+	quality     float64
+	specificity int
+	index       int
+}
+
+// better reports whether n should be preferred over o.
+func (n *negotiation) better(o *negotiation) bool {
+	if n.quality != o.quality {
+		return n.quality > o.quality
+	}
+	if n.specificity != o.specificity {
+		return n.specificity > o.specificity
+	}
+	return n.index < o.index
+}
+
+// negotiate returns the candidate that is preferred by the client.
+// The quality of a candidate is determined by the most specific range that matches it, this way a more specific
+// range with q=0 can exclude a candidate that would be accepted by a wildcard.
+// Ties are resolved by specificity, then by the order of the ranges in the header and finally by the order of the
+// candidates.
+func negotiate(ranges []acceptRange, candidates []string) (string, bool) {
+	var best *negotiation
+	for _, candidate := range candidates {
+		var match *acceptRange
+		for i := range ranges {
+			r := &ranges[i]
+			if !r.matches(candidate) {
+				continue
+			}
+			if match == nil || r.specificity() > match.specificity() {
+				match = r
+			}
+		}
+		if match == nil || match.quality <= 0 {
+			continue
+		}
+		n := &negotiation{
+			mediaType:   candidate,
+			quality:     match.quality,
+			specificity: match.specificity(),
+			index:       match.index,
+		}
+		if best == nil || n.better(best) {
+			best = n
+		}
+	}
+	if best == nil {
+		return "", false
+	}
+	return best.mediaType, true
+}
+
+// structuredSyntaxSuffixes maps the structured syntax suffixes (RFC 6839) to the media type whose encoder should be
+// used for media types with that suffix, e.g. "application/vnd.api+json" can be encoded with "application/json".
+var structuredSyntaxSuffixes = map[string]string{
+	"json": "application/json",
+	"xml":  "application/xml",
+	"cbor": "application/cbor",
+	"yaml": "application/yaml",
+}
+
+// structuredSyntaxSuffix returns the structured syntax suffix of the media type (without the "+").
+func structuredSyntaxSuffix(mediaType string) string {
+	_, subType := splitMediaType(mediaType)
+	i := strings.LastIndexByte(subType, '+')
+	if i < 0 {
+		return ""
+	}
+	return subType[i+1:]
+}
+
+// isSuffixKey reports whether the Encoders key is registered for a structured syntax suffix (e.g. "+json").
+func isSuffixKey(contentType string) bool {
+	return strings.HasPrefix(contentType, "+")
+}
+
+// lookupEncoder returns the encoder for the specified content type.
+// If there is no encoder for the exact content type the structured syntax suffix is used to find an encoder: first
+// an encoder registered for the suffix itself (e.g. "+json") and then the encoder of the suffixes base media type
+// (e.g. "application/json").
+func lookupEncoder(encoders map[string]EncodeFunc, contentType string) EncodeFunc {
+	contentType = strings.ToLower(contentType)
+	if f, ok := encoders[contentType]; ok {
+		return f
+	}
+	suffix := structuredSyntaxSuffix(contentType)
+	if suffix == "" {
+		return nil
+	}
+	if f, ok := encoders["+"+suffix]; ok {
+		return f
+	}
+	if base, ok := structuredSyntaxSuffixes[suffix]; ok {
+		return encoders[base]
+	}
+	return nil
+}
+
+// encoderContentTypes returns the content types of the encoders in a stable order.
+// Encoders that are registered for a structured syntax suffix are left out, since they do not represent a media type.
+func encoderContentTypes(encoders map[string]EncodeFunc) []string {
+	contentTypes := make([]string, 0, len(encoders))
+	for contentType := range encoders {
+		if isSuffixKey(contentType) {
+			continue
+		}
+		contentTypes = append(contentTypes, strings.ToLower(contentType))
+	}
+	sort.Strings(contentTypes)
+	return contentTypes
+}
+
+// isEchoable reports whether an exact media range that is encoded using its structured syntax suffix (e.g.
+// "application/problem+xml" or "application/vnd.api+json") is echoed in the response. Only media types of the
+// application top-level type are echoed: the suffix of other types (e.g. "image/svg+xml") describes the syntax of a
+// document that a generic XML or JSON encoder cannot produce.
+func isEchoable(mediaType string) bool {
+	return strings.HasPrefix(mediaType, "application/")
+}
+
+// candidateContentTypes returns the content types that can be negotiated for the specified media ranges.
+// Besides the registered encoders every exact media range that can be encoded using its structured syntax suffix is a
+// candidate (see isEchoable), so the response echoes the (vendor) media type the client asked for.
+func candidateContentTypes(options *Options, ranges []acceptRange) []string {
+	encoders := options.Encoders
+	candidates := options.encoderRegistry().orderedMediaTypes()
+	for i := range ranges {
+		if ranges[i].specificity() != specificityExact {
+			continue
+		}
+		mediaType := ranges[i].mainType + "/" + ranges[i].subType
+		if _, ok := encoders[mediaType]; ok || !isEchoable(mediaType) {
+			continue
+		}
+		if lookupEncoder(encoders, mediaType) != nil {
+			candidates = append(candidates, mediaType)
+		}
+	}
+	return candidates
+}
+
+// getPreferredContentType returns the encoder and content type that fits the clients Accept header best.
+// Candidates the client accepts equally are resolved in the order of the EncoderRegistry.
+// If the client did not send a (valid) Accept header no encoder is returned and acceptable is true, if none of the
+// encoders satisfies the Accept header acceptable is false.
+func getPreferredContentType(options *Options, r *http.Request) (encoder EncodeFunc, contentType string, acceptable bool) {
+	ranges := parseAccept(r.Header.Values("Accept"))
+	if len(ranges) == 0 {
+		return nil, "", true
+	}
+	contentType, ok := negotiate(ranges, candidateContentTypes(options, ranges))
+	if !ok {
+		return nil, "", false
+	}
+	return lookupEncoder(options.Encoders, contentType), contentType, true
+}
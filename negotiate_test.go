@@ -0,0 +1,409 @@
+package httphandler_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Eun/go-hit"
+
+	"github.com/pkg/errors"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/talon-one/go-httphandler"
+)
+
+// stringEncoder returns an encoder that writes the specified string.
+func stringEncoder(s string) httphandler.EncodeFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ *httphandler.WireError) error {
+		_, err := io.WriteString(w, s)
+		return err
+	}
+}
+
+func TestAcceptNegotiation(t *testing.T) {
+	h := httphandler.New(&httphandler.Options{
+		Encoders: map[string]httphandler.EncodeFunc{
+			"application/json": stringEncoder("json"),
+			"application/xml":  stringEncoder("xml"),
+			"text/html":        stringEncoder("html"),
+		},
+		FallbackEncoderFunc: func() (httphandler.EncodeFunc, string) {
+			return stringEncoder("fallback"), "application/octet-stream"
+		},
+	})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		return &httphandler.HandlerError{}
+	}))
+
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	tests := []struct {
+		name                string
+		accept              []string
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			name:                "browser",
+			accept:              []string{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"},
+			expectedContentType: "text/html",
+			expectedBody:        "html",
+		},
+		{
+			name:                "quality values",
+			accept:              []string{"text/html;q=0.5, application/xml;q=0.7, application/json;q=0.6"},
+			expectedContentType: "application/xml",
+			expectedBody:        "xml",
+		},
+		{
+			name:                "client order on ties",
+			accept:              []string{"application/xml, application/json"},
+			expectedContentType: "application/xml",
+			expectedBody:        "xml",
+		},
+		{
+			name:                "multiple header values",
+			accept:              []string{"image/png", "application/json"},
+			expectedContentType: "application/json",
+			expectedBody:        "json",
+		},
+		{
+			name:                "type wildcard",
+			accept:              []string{"image/png, text/*"},
+			expectedContentType: "text/html",
+			expectedBody:        "html",
+		},
+		{
+			name:                "specific range wins over wildcard",
+			accept:              []string{"*/*;q=0.1, application/*;q=0.5, application/xml;q=0.9"},
+			expectedContentType: "application/xml",
+			expectedBody:        "xml",
+		},
+		{
+			name:                "exclusion with q=0",
+			accept:              []string{"application/*, application/json;q=0"},
+			expectedContentType: "application/xml",
+			expectedBody:        "xml",
+		},
+		{
+			name:                "everything excluded",
+			accept:              []string{"*/*;q=0"},
+			expectedContentType: "application/octet-stream",
+			expectedBody:        "fallback",
+		},
+		{
+			name:                "case insensitive",
+			accept:              []string{"Application/JSON"},
+			expectedContentType: "application/json",
+			expectedBody:        "json",
+		},
+		{
+			name:                "invalid ranges are skipped",
+			accept:              []string{"invalid;;, text/html;q=abc, application/json;q=0.5"},
+			expectedContentType: "application/json",
+			expectedBody:        "json",
+		},
+		{
+			name:                "quoted parameters",
+			accept:              []string{`text/plain;foo="a,b", application/json`},
+			expectedContentType: "application/json",
+			expectedBody:        "json",
+		},
+		{
+			name:                "unknown media type",
+			accept:              []string{"image/png"},
+			expectedContentType: "application/octet-stream",
+			expectedBody:        "fallback",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			steps := []hit.IStep{
+				hit.Get(s.URL),
+			}
+			for _, accept := range test.accept {
+				steps = append(steps, hit.Send().Headers("Accept").Add(accept))
+			}
+			steps = append(steps,
+				hit.Expect().Status().Equal(http.StatusInternalServerError),
+				hit.Expect().Headers("Content-Type").Equal(test.expectedContentType),
+				hit.Expect().Body().String().Equal(test.expectedBody),
+			)
+			hit.Test(t, steps...)
+		})
+	}
+}
+
+func TestStructuredSyntaxSuffix(t *testing.T) {
+	h := httphandler.New(&httphandler.Options{
+		Encoders: map[string]httphandler.EncodeFunc{
+			"application/json": stringEncoder("json"),
+			"application/xml":  stringEncoder("xml"),
+			"+yaml":            stringEncoder("yaml"),
+		},
+	})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		return &httphandler.HandlerError{}
+	}))
+	mux.HandleFunc("/problem", h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		return &httphandler.HandlerError{
+			ContentType: "application/problem+xml",
+		}
+	}))
+
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	t.Run("vendor type uses base encoder", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(s.URL),
+			hit.Send().Headers("Accept").Add("application/vnd.talon.v2+json"),
+			hit.Expect().Headers("Content-Type").Equal("application/vnd.talon.v2+json"),
+			hit.Expect().Body().String().Equal("json"),
+		)
+	})
+
+	t.Run("encoder registered for suffix", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(s.URL),
+			hit.Send().Headers("Accept").Add("application/vnd.talon+yaml"),
+			hit.Expect().Headers("Content-Type").Equal("application/vnd.talon+yaml"),
+			hit.Expect().Body().String().Equal("yaml"),
+		)
+	})
+
+	t.Run("quality is respected", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(s.URL),
+			hit.Send().Headers("Accept").Add("application/problem+xml;q=0.5, application/json"),
+			hit.Expect().Headers("Content-Type").Equal("application/json"),
+			hit.Expect().Body().String().Equal("json"),
+		)
+	})
+
+	t.Run("only application types are echoed", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(s.URL),
+			hit.Send().Headers("Accept").Add("image/svg+xml"),
+			hit.Expect().Headers("Content-Type").Equal("application/json"),
+		)
+		hit.Test(t,
+			hit.Get(s.URL),
+			hit.Send().Headers("Accept").Add("application/problem+xml"),
+			hit.Expect().Headers("Content-Type").Equal("application/problem+xml"),
+			hit.Expect().Body().String().Equal("xml"),
+		)
+		hit.Test(t,
+			hit.Get(s.URL),
+			hit.Send().Headers("Accept").Add("application/prs.talon+xml"),
+			hit.Expect().Headers("Content-Type").Equal("application/prs.talon+xml"),
+			hit.Expect().Body().String().Equal("xml"),
+		)
+	})
+
+	t.Run("strict negotiation", func(t *testing.T) {
+		strict := httptest.NewServer(httphandler.New(&httphandler.Options{
+			Encoders: map[string]httphandler.EncodeFunc{
+				"application/json": stringEncoder("json"),
+				"application/xml":  stringEncoder("xml"),
+			},
+			StrictNegotiation: true,
+		}).HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+			return &httphandler.HandlerError{}
+		}))
+		defer strict.Close()
+		hit.Test(t,
+			hit.Get(strict.URL),
+			hit.Send().Headers("Accept").Add("application/problem+xml"),
+			hit.Expect().Status().Equal(http.StatusInternalServerError),
+			hit.Expect().Headers("Content-Type").Equal("application/problem+xml"),
+			hit.Expect().Body().String().Equal("xml"),
+		)
+		hit.Test(t,
+			hit.Get(strict.URL),
+			hit.Send().Headers("Accept").Add("image/svg+xml"),
+			hit.Expect().Status().Equal(http.StatusNotAcceptable),
+		)
+	})
+
+	t.Run("unknown suffix", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(s.URL),
+			hit.Send().Headers("Accept").Add("application/vnd.talon+cbor"),
+			hit.Expect().Headers("Content-Type").Equal("application/json"),
+		)
+	})
+
+	t.Run("content type of the handler error", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(hit.JoinURL(s.URL, "problem")),
+			hit.Expect().Headers("Content-Type").Equal("application/problem+xml"),
+			hit.Expect().Body().String().Equal("xml"),
+		)
+	})
+}
+
+func TestStrictNegotiation(t *testing.T) {
+	var loggedStatusCode int
+	h := httphandler.New(&httphandler.Options{
+		LogFunc: func(_ *http.Request, handlerError, internalError, publicError error, statusCode int, requestUUID string) {
+			loggedStatusCode = statusCode
+		},
+		Encoders: map[string]httphandler.EncodeFunc{
+			"application/json": stringEncoder("json"),
+			"text/html":        stringEncoder("html"),
+		},
+		StrictNegotiation: true,
+	})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		return &httphandler.HandlerError{
+			StatusCode: http.StatusConflict,
+		}
+	}))
+	mux.HandleFunc("/content-type", h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		return &httphandler.HandlerError{
+			StatusCode:  http.StatusConflict,
+			ContentType: "application/json",
+		}
+	}))
+
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	t.Run("unsatisfiable accept header", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(s.URL),
+			hit.Send().Headers("Accept").Add("image/png"),
+			hit.Expect().Status().Equal(http.StatusNotAcceptable),
+			hit.Expect().Headers("Content-Type").Equal("text/plain; charset=utf-8"),
+			hit.Expect().Body().String().Contains("available media types are: application/json, text/html"),
+		)
+		require.Equal(t, http.StatusConflict, loggedStatusCode)
+	})
+
+	t.Run("no accept header", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(s.URL),
+			hit.Expect().Status().Equal(http.StatusConflict),
+			hit.Expect().Headers("Content-Type").Equal("application/json"),
+		)
+	})
+
+	t.Run("content type of the handler error", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(hit.JoinURL(s.URL, "content-type")),
+			hit.Send().Headers("Accept").Add("image/png"),
+			hit.Expect().Status().Equal(http.StatusConflict),
+			hit.Expect().Body().String().Equal("json"),
+		)
+	})
+
+	t.Run("custom not acceptable encoder", func(t *testing.T) {
+		require.NoError(t, h.SetNotAcceptableEncoder("application/json",
+			func(w http.ResponseWriter, r *http.Request, e *httphandler.WireError) error {
+				var notAcceptableError *httphandler.NotAcceptableError
+				require.True(t, errors.As(e.Error, &notAcceptableError))
+				require.Equal(t, []string{"application/json", "text/html"}, notAcceptableError.AvailableMediaTypes)
+				_, err := io.WriteString(w, "not acceptable")
+				return err
+			}))
+		hit.Test(t,
+			hit.Get(s.URL),
+			hit.Send().Headers("Accept").Add("image/png"),
+			hit.Expect().Status().Equal(http.StatusNotAcceptable),
+			hit.Expect().Headers("Content-Type").Equal("application/json"),
+			hit.Expect().Body().String().Equal("not acceptable"),
+		)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		h.SetStrictNegotiation(false)
+		hit.Test(t,
+			hit.Get(s.URL),
+			hit.Send().Headers("Accept").Add("image/png"),
+			hit.Expect().Status().Equal(http.StatusConflict),
+			hit.Expect().Headers("Content-Type").Equal("application/json"),
+		)
+	})
+}
+
+func TestVaryHeader(t *testing.T) {
+	h := httphandler.New(&httphandler.Options{
+		FormatSources: []httphandler.FormatSource{
+			httphandler.FormatFromQuery("format"),
+			httphandler.FormatFromHeader("X-Format"),
+		},
+		StrictNegotiation: true,
+	})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		w.Header().Add("Vary", "Origin, accept")
+		return &httphandler.HandlerError{}
+	}))
+	mux.HandleFunc("/content-type", h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		return &httphandler.HandlerError{
+			ContentType:     "application/json",
+			ContentLanguage: "de",
+		}
+	}))
+	mux.HandleFunc("/wildcard", h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		w.Header().Set("Vary", "*")
+		return &httphandler.HandlerError{}
+	}))
+
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	t.Run("accept header", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(s.URL),
+			hit.Expect().Headers("Vary").Equal("Origin, Accept, X-Format"),
+			hit.Expect().Headers("Content-Language").Empty(),
+		)
+	})
+
+	t.Run("format override", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(s.URL+"/?format=json"),
+			hit.Expect().Headers("Vary").Equal("Origin, accept"),
+		)
+		hit.Test(t,
+			hit.Get(s.URL),
+			hit.Send().Headers("X-Format").Add("json"),
+			hit.Expect().Headers("Vary").Equal("Origin, Accept, X-Format"),
+		)
+	})
+
+	t.Run("not acceptable", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(s.URL),
+			hit.Send().Headers("Accept").Add("image/png"),
+			hit.Expect().Status().Equal(http.StatusNotAcceptable),
+			hit.Expect().Headers("Vary").Equal("Origin, Accept, X-Format"),
+		)
+	})
+
+	t.Run("content type of the handler error", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(hit.JoinURL(s.URL, "content-type")),
+			hit.Expect().Headers("Vary").Empty(),
+			hit.Expect().Headers("Content-Language").Equal("de"),
+		)
+	})
+
+	t.Run("wildcard", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(hit.JoinURL(s.URL, "wildcard")),
+			hit.Expect().Headers("Vary").Equal("*"),
+		)
+	})
+}
@@ -2,13 +2,11 @@ package httphandler
 
 import (
 	"context"
-	"encoding/json"
+	"encoding"
 	"encoding/xml"
-	"fmt"
 	"io"
 	"log"
 	"net/http"
-	"strconv"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -28,6 +26,10 @@ type Options struct {
 	// If LogFunc is nil the default logger will be used.
 	LogFunc LogFunc
 	// Encoders is a map of Content-Type and EncodeFunc, it will be used to lookup the encoder for the Content-Type.
+	// Media types with a structured syntax suffix (e.g. "application/vnd.api+json") that have no encoder on their own
+	// use the encoder registered for the suffix (e.g. "+json") or the encoder of the suffixes base media type
+	// (e.g. "application/json").
+	// The EncoderRegistry of the Handler (see Handler.Encoders) keeps this map in sync and adds an order and metadata.
 	// If Encoder is nil the default encoders will be used.
 	Encoders map[string]EncodeFunc
 	// FallbackEncoderFunc should return a fallback encoder in case the error Content-Type does not exist in the
@@ -41,6 +43,58 @@ type Options struct {
 	RequestUUIDFunc func() string
 	// CustomPanicHandler it's called when a panic occurs in the HTTP handler. It gets the request context value.
 	CustomPanicHandler PanicHandler
+	// StrictNegotiation enables the strict negotiation mode: if the clients Accept header cannot be satisfied by any
+	// of the Encoders (and the HandlerError does not specify a ContentType) HandleFunc responds with
+	// 406 Not Acceptable instead of using the FallbackEncoderFunc.
+	StrictNegotiation bool
+	// NotAcceptableEncoderFunc should return the encoder that will be used to send the 406 Not Acceptable response
+	// in strict negotiation mode. The Error of the WireError will be a *NotAcceptableError.
+	// If NotAcceptableEncoderFunc is nil the default not acceptable encoder will be used.
+	NotAcceptableEncoderFunc func() (EncodeFunc, string)
+	// FormatSources are consulted in order to find a format override (e.g. ?format=json or /users.xml), the first
+	// source that returns a valid format selects the encoder. A format is valid if it is one of the FormatAliases or
+	// one of the content types of the Encoders.
+	// The precedence is: HandlerError.ContentType, FormatSources, the clients Accept header and FallbackEncoderFunc.
+	// If FormatSources is nil no format override will be used.
+	FormatSources []FormatSource
+	// FormatAliases is a map of format aliases and Content-Type, it will be used to resolve formats of the
+	// FormatSources (e.g. "json" to "application/json").
+	// If FormatAliases is nil the default aliases will be used.
+	FormatAliases map[string]string
+	// Catalog holds the messages that are used to localize a LocalizedError in the PublicError of a HandlerError.
+	// If Catalog is nil LocalizedErrors will not be localized.
+	Catalog *Catalog
+	// LanguageFunc returns the languages the client prefers, they are used to look up the messages in the Catalog.
+	// If LanguageFunc is nil the default language func (that uses the Accept-Language header) will be used.
+	LanguageFunc LanguageFunc
+	// ProblemDetails enables problem details (RFC 9457) for clients that negotiated a generic content type: a response
+	// that would be send as "application/json" will be send as "application/problem+json" using the encoder that is
+	// registered for "application/problem+json", the same applies to "application/xml" and "text/xml" with
+	// "application/problem+xml".
+	// If enabled New adds the ProblemJSONEncoder and the ProblemXMLEncoder for these media types, unless there are
+	// encoders for them already.
+	ProblemDetails bool
+	// ValueEncoders is a map of Content-Type and ValueEncodeFunc, it will be used by Respond to encode the values of
+	// successful responses. The EncoderRegistry keeps this map in sync as well: its order applies to the value encoders
+	// and aliases and removals of media types include their value encoders.
+	// If ValueEncoders is nil the default value encoders (JSON, XML and plain text) will be used.
+	ValueEncoders map[string]ValueEncodeFunc
+	// Decoders is a map of Content-Type and DecodeFunc, it will be used by Decode to lookup the decoder for the
+	// Content-Type of the request.
+	// If Decoders is nil the default decoders (JSON, XML, form and YAML) will be used.
+	Decoders map[string]DecodeFunc
+	// MaxBodySize is the maximum size in bytes of request bodies that are decoded by Decode, larger bodies result in
+	// 413 Request Entity Too Large. A negative size disables the limit.
+	// If MaxBodySize is 0 the DefaultMaxBodySize will be used.
+	MaxBodySize int64
+	// ErrorMappers convert the errors that are returned by handlers wrapped with HandleErrorFunc into HandlerErrors,
+	// they are consulted in order and the first mapper that handles the error wins. Errors no mapper handles result in
+	// 500 Internal Server Error.
+	// If ErrorMappers is nil the DefaultErrorMappers will be used.
+	ErrorMappers []ErrorMapper
+
+	// registry holds the Encoders that have been registered with the EncoderRegistry.
+	registry *EncoderRegistry
 }
 
 // SetLogFunc sets the log function that will be called in case of error.
@@ -60,6 +114,12 @@ func (o *Options) SetEncoders(encoders map[string]EncodeFunc) error {
 	}
 	for contentType, encoder := range encoders {
 		o.Encoders[strings.ToLower(contentType)] = encoder
+		if o.registry != nil {
+			o.registry.forget(strings.ToLower(contentType))
+		}
+	}
+	if o.registry != nil {
+		o.registry.update()
 	}
 	return nil
 }
@@ -76,6 +136,10 @@ func (o *Options) SetEncoder(contentType string, encoder EncodeFunc) error {
 		o.Encoders = make(map[string]EncodeFunc)
 	}
 	o.Encoders[strings.ToLower(contentType)] = encoder
+	if o.registry != nil {
+		o.registry.forget(strings.ToLower(contentType))
+		o.registry.update()
+	}
 	return nil
 }
 
@@ -91,6 +155,10 @@ func (o *Options) SetFallbackEncoder(contentType string, encoder EncodeFunc) err
 	o.FallbackEncoderFunc = func() (EncodeFunc, string) {
 		return encoder, contentType
 	}
+	if o.registry != nil {
+		// the fallback encoder is preferred over the other unregistered encoders
+		o.registry.update()
+	}
 	return nil
 }
 
@@ -110,14 +178,176 @@ func (o *Options) SetCustomPanicHandler(f PanicHandler) {
 	o.CustomPanicHandler = f
 }
 
+// SetStrictNegotiation enables or disables the strict negotiation mode.
+// In strict negotiation mode an unsatisfiable Accept header results in a 406 Not Acceptable response.
+func (o *Options) SetStrictNegotiation(strict bool) {
+	o.StrictNegotiation = strict
+}
+
+// SetNotAcceptableEncoder sets the encoder that will be used to send the 406 Not Acceptable response in strict
+// negotiation mode.
+func (o *Options) SetNotAcceptableEncoder(contentType string, encoder EncodeFunc) error {
+	if contentType == "" {
+		return errors.New("content-type cannot be empty")
+	}
+	if encoder == nil {
+		return errors.New("encoder cannot be nil")
+	}
+	o.NotAcceptableEncoderFunc = func() (EncodeFunc, string) {
+		return encoder, contentType
+	}
+	return nil
+}
+
+// SetFormatSources sets the sources that are consulted (in order) for a format override before the clients Accept
+// header is used.
+func (o *Options) SetFormatSources(sources ...FormatSource) {
+	o.FormatSources = sources
+}
+
+// SetFormatAlias sets the content type for a format alias (e.g. "json" for "application/json").
+func (o *Options) SetFormatAlias(alias, contentType string) error {
+	if alias == "" {
+		return errors.New("alias cannot be empty")
+	}
+	if contentType == "" {
+		return errors.New("content-type cannot be empty")
+	}
+	if o.FormatAliases == nil {
+		o.FormatAliases = make(map[string]string)
+	}
+	o.FormatAliases[strings.ToLower(alias)] = strings.ToLower(contentType)
+	return nil
+}
+
+// SetCatalog sets the Catalog that is used to localize a LocalizedError in the PublicError of a HandlerError.
+func (o *Options) SetCatalog(catalog *Catalog) {
+	o.Catalog = catalog
+}
+
+// SetLanguageFunc sets the function that returns the languages the client prefers.
+// It can be used to override the language detection (e.g. to use a cookie or a user setting).
+func (o *Options) SetLanguageFunc(languageFunc LanguageFunc) error {
+	if languageFunc == nil {
+		return errors.New("languageFunc cannot be nil")
+	}
+	o.LanguageFunc = languageFunc
+	return nil
+}
+
+// SetProblemDetails enables or disables problem details (RFC 9457) for clients that negotiated a generic content type
+// (e.g. "application/json").
+// Enabling adds the ProblemJSONEncoder and the ProblemXMLEncoder for the problem details media types that have no
+// encoder yet, disabling keeps the encoders.
+func (o *Options) SetProblemDetails(enabled bool) {
+	o.ProblemDetails = enabled
+	if enabled {
+		o.addProblemEncoders()
+	}
+}
+
+// SetYAMLEncoder sets the encoder for all YAML media types (see YAMLContentTypes).
+//
+// Example:
+//
+//	handler.SetYAMLEncoder(DefaultYAMLEncoder())
+func (o *Options) SetYAMLEncoder(encoder EncodeFunc) error {
+	if encoder == nil {
+		return errors.New("encoder cannot be nil")
+	}
+	for _, contentType := range YAMLContentTypes {
+		if err := o.SetEncoder(contentType, encoder); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetValueEncoder sets one specific value encoder in the ValueEncoders map.
+func (o *Options) SetValueEncoder(contentType string, encoder ValueEncodeFunc) error {
+	if contentType == "" {
+		return errors.New("content-type cannot be empty")
+	}
+	if encoder == nil {
+		return errors.New("encoder cannot be nil")
+	}
+	o.encoderRegistry().setValueEncodeFunc(strings.ToLower(contentType), encoder)
+	return nil
+}
+
+// SetDecoders sets the decoders of the specified map of content type and DecodeFunc in the Decoders, decoders for
+// other content types are kept (see SetEncoders).
+func (o *Options) SetDecoders(decoders map[string]DecodeFunc) error {
+	if decoders == nil {
+		return errors.New("decoders cannot be nil")
+	}
+	for contentType, decoder := range decoders {
+		if err := o.SetDecoder(contentType, decoder); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetDecoder sets one specific decoder in the Decoders map.
+func (o *Options) SetDecoder(contentType string, decoder DecodeFunc) error {
+	if contentType == "" {
+		return errors.New("content-type cannot be empty")
+	}
+	if decoder == nil {
+		return errors.New("decoder cannot be nil")
+	}
+	if o.Decoders == nil {
+		o.Decoders = make(map[string]DecodeFunc)
+	}
+	o.Decoders[strings.ToLower(contentType)] = decoder
+	return nil
+}
+
+// SetMaxBodySize sets the maximum size in bytes of request bodies that are decoded by Decode.
+func (o *Options) SetMaxBodySize(size int64) {
+	o.MaxBodySize = size
+}
+
+// SetErrorMappers sets the ErrorMappers, the built-in mappers are only used if they are part of mappers
+// (see DefaultErrorMappers).
+func (o *Options) SetErrorMappers(mappers ...ErrorMapper) error {
+	for _, mapper := range mappers {
+		if mapper == nil {
+			return errors.New("mapper cannot be nil")
+		}
+	}
+	o.ErrorMappers = mappers
+	return nil
+}
+
+// AddErrorMapper adds the mapper in front of the ErrorMappers, so it takes precedence over the mappers that have been
+// added before and the built-in mappers.
+func (o *Options) AddErrorMapper(mapper ErrorMapper) error {
+	if mapper == nil {
+		return errors.New("mapper cannot be nil")
+	}
+	o.ErrorMappers = append([]ErrorMapper{mapper}, o.ErrorMappers...)
+	return nil
+}
+
 func defaultOptions() *Options {
-	return &Options{
-		LogFunc:             defaultLogFunc(),
-		Encoders:            defaultEncoders(),
-		FallbackEncoderFunc: defaultFallbackEncoder(),
-		RequestUUIDFunc:     defaultRequestUUID(),
-		CustomPanicHandler:  defaultCustomPanicHandler(),
+	o := &Options{
+		LogFunc:                  defaultLogFunc(),
+		Encoders:                 defaultEncoders(),
+		FallbackEncoderFunc:      defaultFallbackEncoder(),
+		RequestUUIDFunc:          defaultRequestUUID(),
+		CustomPanicHandler:       defaultCustomPanicHandler(),
+		NotAcceptableEncoderFunc: defaultNotAcceptableEncoder(),
+		FormatAliases:            defaultFormatAliases(),
+		LanguageFunc:             defaultLanguageFunc(),
+		ValueEncoders:            defaultValueEncoders(),
+		Decoders:                 defaultDecoders(),
+		ErrorMappers:             DefaultErrorMappers(),
 	}
+	o.registry = newEncoderRegistry(o)
+	_ = o.registry.Register(defaultTextEncoder())
+	return o
 }
 
 func defaultLogFunc() LogFunc {
@@ -137,10 +367,18 @@ func defaultEncoders() map[string]EncodeFunc {
 		"application/json": DefaultJSONEncoder(),
 		"application/xml":  DefaultXMLEncoder(),
 		"text/html":        DefaultHTMLEncoder(),
+		"text/plain":       DefaultTextEncoder(),
 		"text/xml":         DefaultXMLEncoder(),
 	}
 }
 
+// defaultTextEncoder returns the DefaultTextEncoder as Encoder, so its Content-Type is sent with charset=utf-8.
+// It has a media type, so registering it cannot fail.
+// The negative weight ranks it after the other default encoders if the client accepts several media types equally.
+func defaultTextEncoder() Encoder {
+	return WrapEncodeFunc(DefaultTextEncoder(), "text/plain").WithCharset("utf-8").WithWeight(-1)
+}
+
 func defaultFallbackEncoder() func() (EncodeFunc, string) {
 	return func() (EncodeFunc, string) {
 		return DefaultJSONEncoder(), "application/json"
@@ -157,91 +395,96 @@ func defaultCustomPanicHandler() PanicHandler {
 	return func(ctx context.Context, err *HandlerError) {}
 }
 
-// DefaultJSONEncoder implements the default JSON encoder that will be used.
-func DefaultJSONEncoder() EncodeFunc {
-	return func(w http.ResponseWriter, r *http.Request, e *WireError) error {
-		errToSend := struct {
-			StatusCode  *int
-			Error       interface{}
-			RequestUUID *string
-		}{
-			StatusCode:  &e.StatusCode,
-			RequestUUID: &e.RequestUUID,
-		}
-
-		// marshal the Error before everything else
-		buf, err := json.Marshal(e.Error)
-		if err != nil {
-			return errors.Wrap(err, "unable to encode error")
-		}
-
-		// if the error message is empty use the Error() function
-		if len(buf) == 0 || string(buf) == "{}" || string(buf) == "null" {
-			errToSend.Error = e.Error.Error()
-		} else {
-			errToSend.Error = json.RawMessage(buf)
-		}
+func defaultFormatAliases() map[string]string {
+	return map[string]string{
+		"json": "application/json",
+		"xml":  "application/xml",
+		"html": "text/html",
+		"text": "text/plain",
+		"yaml": "application/yaml",
+	}
+}
 
-		return json.NewEncoder(w).Encode(errToSend)
+func defaultNotAcceptableEncoder() func() (EncodeFunc, string) {
+	return func() (EncodeFunc, string) {
+		return DefaultNotAcceptableEncoder(), "text/plain; charset=utf-8"
 	}
 }
 
+// DefaultJSONEncoder implements the default JSON encoder that will be used.
+// Use JSONEncoder to configure the field names and the format.
+func DefaultJSONEncoder() EncodeFunc {
+	return JSONEncoder(JSONEncoderOptions{})
+}
+
 // DefaultXMLEncoder implements the default XML encoder that will be used.
+// It renders the WireError using the schema described in wireerror.xsd, without a namespace.
 func DefaultXMLEncoder() EncodeFunc {
-	return func(w http.ResponseWriter, r *http.Request, e *WireError) error {
-		errToSend := struct {
-			StatusCode  *int
-			Error       interface{}
-			RequestUUID *string
-		}{
-			StatusCode:  &e.StatusCode,
-			RequestUUID: &e.RequestUUID,
-		}
-
-		// marshal the Error before everything else
-		buf, err := xml.Marshal(e.Error)
-		if err != nil {
-			return errors.Wrap(err, "unable to encode error")
-		}
-
-		// if the error message is empty use the Error() function
-		if len(buf) == 0 || string(buf) == "<errorString></errorString>" {
-			errToSend.Error = json.RawMessage(e.Error.Error())
-		} else {
-			errToSend.Error = buf
-		}
-
-		return xml.NewEncoder(w).Encode(errToSend)
-	}
+	return XMLEncoder("")
 }
 
-// DefaultHTMLEncoder implements the default HTML encoder that will be used.
-func DefaultHTMLEncoder() EncodeFunc {
+// XMLEncoder implements an XML encoder that renders the WireError using the schema described in wireerror.xsd:
+//
+//	<?xml version="1.0" encoding="UTF-8"?>
+//	<WireError>
+//	    <StatusCode>404</StatusCode>
+//	    <Error>user not found</Error>
+//	    <RequestUUID>6ba7b810-9dad-11d1-80b4-00c04fd430c8</RequestUUID>
+//	</WireError>
+//
+// If the Error implements xml.Marshaler or encoding.TextMarshaler it is used to render the content of the Error
+// element, otherwise the escaped message of the Error is used.
+// If namespace is not empty it will be used as the default namespace of the document.
+func XMLEncoder(namespace string) EncodeFunc {
 	return func(w http.ResponseWriter, r *http.Request, e *WireError) error {
-		if _, err := io.WriteString(w, "<!DOCTYPE html><html><head><title>"); err != nil {
-			return err
-		}
-		if _, err := io.WriteString(w, strconv.Itoa(e.StatusCode)); err != nil {
+		if _, err := io.WriteString(w, xml.Header); err != nil {
 			return err
 		}
-		if _, err := io.WriteString(w, " Error</title></head><body><h1>"); err != nil {
-			return err
+		enc := xml.NewEncoder(w)
+		root := xml.StartElement{Name: xml.Name{Local: "WireError"}}
+		if namespace != "" {
+			root.Attr = []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: namespace}}
 		}
-		if _, err := fmt.Fprintf(w, "%#v", e.Error); err != nil {
+		if err := enc.EncodeToken(root); err != nil {
 			return err
 		}
-		if _, err := io.WriteString(w, "<hr>"); err != nil {
+		if err := enc.EncodeElement(e.StatusCode, xml.StartElement{Name: xml.Name{Local: "StatusCode"}}); err != nil {
 			return err
 		}
-		if _, err := io.WriteString(w, "<p>RequestUUID: <code>"); err != nil {
-			return err
+		if err := encodeXMLError(enc, e.Error); err != nil {
+			return errors.Wrap(err, "unable to encode error")
 		}
-		if _, err := io.WriteString(w, e.RequestUUID); err != nil {
+		if err := enc.EncodeElement(e.RequestUUID, xml.StartElement{Name: xml.Name{Local: "RequestUUID"}}); err != nil {
 			return err
 		}
-		if _, err := io.WriteString(w, "</code></p></body></html>"); err != nil {
+		if err := enc.EncodeToken(root.End()); err != nil {
 			return err
 		}
-		return nil
+		return enc.Flush()
 	}
 }
+
+// encodeXMLError encodes the Error element of the WireError.
+func encodeXMLError(enc *xml.Encoder, err error) error {
+	start := xml.StartElement{Name: xml.Name{Local: "Error"}}
+	switch err.(type) {
+	case nil:
+		return enc.EncodeElement("", start)
+	case xml.Marshaler, encoding.TextMarshaler:
+		return enc.EncodeElement(err, start)
+	default:
+		return enc.EncodeElement(err.Error(), start)
+	}
+}
+
+// DefaultHTMLEncoder implements the default HTML encoder that will be used.
+// It renders a simple error page, use HTMLEncoder to render custom templates.
+func DefaultHTMLEncoder() EncodeFunc {
+	return HTMLEncoder(defaultHTMLTemplates)
+}
+
+// DefaultNotAcceptableEncoder implements the default encoder for 406 Not Acceptable responses.
+// It writes the error message as plain text, see DefaultTextEncoder.
+func DefaultNotAcceptableEncoder() EncodeFunc {
+	return DefaultTextEncoder()
+}
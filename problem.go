@@ -0,0 +1,307 @@
+package httphandler
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// ProblemRequestUUIDMember is the name of the extension member that holds the RequestUUID in problem details.
+const ProblemRequestUUIDMember = "requestUUID"
+
+// Problem is a PublicError that carries the members of a problem details object as defined in RFC 9457.
+// It will be rendered by the problem details encoders (e.g. ProblemJSONEncoder), other encoders render the Detail
+// (or Title) as message.
+//
+// Example:
+//
+//	return &HandlerError{
+//	    StatusCode: http.StatusForbidden,
+//	    PublicError: &Problem{
+//	        Type:   "https://example.com/probs/out-of-credit",
+//	        Title:  "You do not have enough credit.",
+//	        Detail: "Your current balance is 30, but that costs 50.",
+//	        Extensions: map[string]interface{}{
+//	            "balance": 30,
+//	        },
+//	    },
+//	}
+type Problem struct {
+	// Type is a URI reference that identifies the problem type. If not specified "about:blank" will be used.
+	Type string
+	// Title is a short, human-readable summary of the problem type. If not specified and Type is "about:blank" the
+	// status text of the status code will be used.
+	Title string
+	// Detail is a human-readable explanation specific to this occurrence of the problem.
+	Detail string
+	// Instance is a URI reference that identifies the specific occurrence of the problem.
+	Instance string
+	// Extensions are additional members of the problem details object. Extensions that have the name of one of
+	// the standard members are ignored.
+	Extensions map[string]interface{}
+}
+
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Detail
+	}
+	if p.Title != "" {
+		return p.Title
+	}
+	return p.Type
+}
+
+// problemDetails returns the problem details for the WireError.
+// If the Error of the WireError is not a *Problem a problem of the type "about:blank" is created that uses the error
+// message as detail.
+func problemDetails(e *WireError) *Problem {
+	var problem Problem
+	var p *Problem
+	if errors.As(e.Error, &p) {
+		problem = *p
+	} else if e.Error != nil {
+		problem.Detail = e.Error.Error()
+	}
+	if problem.Type == "" {
+		problem.Type = "about:blank"
+	}
+	if problem.Title == "" && problem.Type == "about:blank" {
+		problem.Title = statusText(e.StatusCode)
+	}
+	return &problem
+}
+
+// isProblemMember reports whether name is one of the standard members of a problem details object.
+func isProblemMember(name string) bool {
+	switch name {
+	case "type", "title", "status", "detail", "instance":
+		return true
+	default:
+		return false
+	}
+}
+
+// ProblemJSONEncoder implements an encoder for problem details (RFC 9457) in JSON.
+// It is not a default encoder, enabling problem details adds it for "application/problem+json" (see
+// Options.ProblemDetails). The RequestUUID is added as extension member (see ProblemRequestUUIDMember).
+func ProblemJSONEncoder() EncodeFunc {
+	return func(w http.ResponseWriter, r *http.Request, e *WireError) error {
+		problem := problemDetails(e)
+
+		members := make(map[string]interface{})
+		for name, value := range problem.Extensions {
+			if !isProblemMember(name) {
+				members[name] = value
+			}
+		}
+		if e.RequestUUID != "" {
+			members[ProblemRequestUUIDMember] = e.RequestUUID
+		}
+		members["type"] = problem.Type
+		if problem.Title != "" {
+			members["title"] = problem.Title
+		}
+		members["status"] = e.StatusCode
+		if problem.Detail != "" {
+			members["detail"] = problem.Detail
+		}
+		if problem.Instance != "" {
+			members["instance"] = problem.Instance
+		}
+
+		return json.NewEncoder(w).Encode(members)
+	}
+}
+
+// ProblemXMLNamespace is the XML namespace of problem details, see RFC 9457 Appendix B.
+const ProblemXMLNamespace = "urn:ietf:rfc:7807"
+
+// ProblemXMLEncoder implements an encoder for problem details (RFC 9457) in XML.
+// It is not a default encoder, enabling problem details adds it for "application/problem+xml" (see
+// Options.ProblemDetails). The RequestUUID is added as extension member (see ProblemRequestUUIDMember).
+// Extension members are rendered as described in RFC 9457 Appendix B: objects as nested elements and arrays as a
+// sequence of <i> elements. Extension members whose name is not a valid XML element name are left out.
+func ProblemXMLEncoder() EncodeFunc {
+	return func(w http.ResponseWriter, r *http.Request, e *WireError) error {
+		problem := problemDetails(e)
+
+		if _, err := io.WriteString(w, xml.Header); err != nil {
+			return err
+		}
+		enc := xml.NewEncoder(w)
+		root := xml.StartElement{
+			Name: xml.Name{Local: "problem"},
+			Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: ProblemXMLNamespace}},
+		}
+		if err := enc.EncodeToken(root); err != nil {
+			return err
+		}
+
+		members := []struct {
+			name  string
+			value string
+		}{
+			{"type", problem.Type},
+			{"title", problem.Title},
+			{"status", strconv.Itoa(e.StatusCode)},
+			{"detail", problem.Detail},
+			{"instance", problem.Instance},
+		}
+		for _, member := range members {
+			if member.value == "" {
+				continue
+			}
+			if err := encodeXMLValue(enc, member.name, member.value); err != nil {
+				return err
+			}
+		}
+
+		extensions := make(map[string]interface{}, len(problem.Extensions)+1)
+		for name, value := range problem.Extensions {
+			if !isProblemMember(name) {
+				extensions[name] = value
+			}
+		}
+		if e.RequestUUID != "" {
+			extensions[ProblemRequestUUIDMember] = e.RequestUUID
+		}
+		if err := encodeXMLMembers(enc, extensions); err != nil {
+			return err
+		}
+
+		if err := enc.EncodeToken(root.End()); err != nil {
+			return err
+		}
+		return enc.Flush()
+	}
+}
+
+// encodeXMLMembers encodes the members sorted by their name.
+func encodeXMLMembers(enc *xml.Encoder, members map[string]interface{}) error {
+	names := make([]string, 0, len(members))
+	for name := range members {
+		if isXMLName(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value, err := genericValue(members[name])
+		if err != nil {
+			return errors.Wrapf(err, "unable to encode member %q", name)
+		}
+		if err := encodeXMLValue(enc, name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeXMLValue encodes a generic value (as returned by genericValue) as element with the specified name.
+func encodeXMLValue(enc *xml.Encoder, name string, value interface{}) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if err := encodeXMLMembers(enc, v); err != nil {
+			return err
+		}
+	case []interface{}:
+		for _, item := range v {
+			if err := encodeXMLValue(enc, "i", item); err != nil {
+				return err
+			}
+		}
+	case nil:
+	default:
+		if err := enc.EncodeToken(xml.CharData(fmt.Sprint(v))); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// genericValue converts v into its generic JSON representation (maps, slices, strings, numbers and booleans), so
+// arbitrary extension values can be rendered in formats other than JSON.
+func genericValue(v interface{}) (interface{}, error) {
+	switch v.(type) {
+	case string, bool, nil:
+		return v, nil
+	}
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// isXMLName reports whether name can be used as XML element name.
+func isXMLName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case unicode.IsLetter(c) || c == '_':
+		case i > 0 && (unicode.IsDigit(c) || c == '-' || c == '.'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// problemContentTypes maps content types to their problem details counterpart.
+var problemContentTypes = map[string]string{
+	"application/json": "application/problem+json",
+	"application/xml":  "application/problem+xml",
+	"text/xml":         "application/problem+xml",
+}
+
+// defaultProblemEncoders returns the encoders that are added for the problem details media types if problem details
+// are enabled.
+func defaultProblemEncoders() map[string]EncodeFunc {
+	return map[string]EncodeFunc{
+		"application/problem+json": ProblemJSONEncoder(),
+		"application/problem+xml":  ProblemXMLEncoder(),
+	}
+}
+
+// addProblemEncoders adds the default problem details encoders for the media types that have no encoder yet.
+func (o *Options) addProblemEncoders() {
+	for contentType, encoder := range defaultProblemEncoders() {
+		if _, ok := o.Encoders[contentType]; !ok {
+			_ = o.SetEncoder(contentType, encoder)
+		}
+	}
+}
+
+// getProblemEncoder returns the problem details encoder and content type for the specified content type if the
+// Encoders contain an encoder for its problem details counterpart (e.g. "application/problem+json" for
+// "application/json"). Otherwise the specified encoder and content type are returned.
+func getProblemEncoder(options *Options, encoder EncodeFunc, contentType string) (EncodeFunc, string) {
+	problemContentType, ok := problemContentTypes[contentType]
+	if !ok {
+		return encoder, contentType
+	}
+	if f, ok := options.Encoders[problemContentType]; ok {
+		return f, problemContentType
+	}
+	return encoder, contentType
+}
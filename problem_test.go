@@ -0,0 +1,209 @@
+package httphandler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Eun/go-hit"
+
+	"github.com/pkg/errors"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/talon-one/go-httphandler"
+)
+
+func TestProblemJSONEncoder(t *testing.T) {
+	h := httphandler.New(nil)
+	require.NoError(t, h.SetRequestUUIDFunc(func() string {
+		return "0123456789"
+	}))
+	require.NoError(t, h.SetEncoder("application/problem+json", httphandler.ProblemJSONEncoder()))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/problem", h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		return &httphandler.HandlerError{
+			StatusCode: http.StatusForbidden,
+			PublicError: errors.Wrap(&httphandler.Problem{
+				Type:     "https://example.com/probs/out-of-credit",
+				Title:    "You do not have enough credit.",
+				Detail:   "Your current balance is 30, but that costs 50.",
+				Instance: "/account/12345/msgs/abc",
+				Extensions: map[string]interface{}{
+					"balance": 30,
+					"status":  "ignored",
+				},
+			}, "wrapped"),
+		}
+	}))
+	mux.HandleFunc("/error", h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		return &httphandler.HandlerError{
+			StatusCode:  http.StatusNotFound,
+			PublicError: errors.New("user not found"),
+		}
+	}))
+
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	t.Run("problem", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(hit.JoinURL(s.URL, "problem")),
+			hit.Send().Headers("Accept").Add("application/problem+json"),
+			hit.Expect().Status().Equal(http.StatusForbidden),
+			hit.Expect().Headers("Content-Type").Equal("application/problem+json"),
+			hit.Expect().Body().JSON().Equal(map[string]interface{}{
+				"type":        "https://example.com/probs/out-of-credit",
+				"title":       "You do not have enough credit.",
+				"status":      http.StatusForbidden,
+				"detail":      "Your current balance is 30, but that costs 50.",
+				"instance":    "/account/12345/msgs/abc",
+				"balance":     30,
+				"requestUUID": "0123456789",
+			}),
+		)
+	})
+
+	t.Run("plain error", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(hit.JoinURL(s.URL, "error")),
+			hit.Send().Headers("Accept").Add("application/problem+json"),
+			hit.Expect().Status().Equal(http.StatusNotFound),
+			hit.Expect().Headers("Content-Type").Equal("application/problem+json"),
+			hit.Expect().Body().JSON().Equal(map[string]interface{}{
+				"type":        "about:blank",
+				"title":       "Not Found",
+				"status":      http.StatusNotFound,
+				"detail":      "user not found",
+				"requestUUID": "0123456789",
+			}),
+		)
+	})
+
+	t.Run("application/json without problem details", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(hit.JoinURL(s.URL, "error")),
+			hit.Send().Headers("Accept").Add("application/json"),
+			hit.Expect().Headers("Content-Type").Equal("application/json"),
+			hit.Expect().Body().JSON().JQ(".Error").Equal("user not found"),
+		)
+	})
+
+	t.Run("application/json with problem details", func(t *testing.T) {
+		h.SetProblemDetails(true)
+		defer h.SetProblemDetails(false)
+		hit.Test(t,
+			hit.Get(hit.JoinURL(s.URL, "error")),
+			hit.Send().Headers("Accept").Add("application/json"),
+			hit.Expect().Headers("Content-Type").Equal("application/problem+json"),
+			hit.Expect().Body().JSON().JQ(".detail").Equal("user not found"),
+		)
+		hit.Test(t,
+			hit.Get(hit.JoinURL(s.URL, "error")),
+			hit.Expect().Headers("Content-Type").Equal("application/problem+json"),
+			hit.Expect().Body().JSON().JQ(".detail").Equal("user not found"),
+		)
+	})
+}
+
+func TestProblemXMLEncoder(t *testing.T) {
+	h := httphandler.New(nil)
+	require.NoError(t, h.SetRequestUUIDFunc(func() string {
+		return "0123456789"
+	}))
+	require.NoError(t, h.SetEncoder("application/problem+xml", httphandler.ProblemXMLEncoder()))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/problem", h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		return &httphandler.HandlerError{
+			StatusCode: http.StatusForbidden,
+			PublicError: &httphandler.Problem{
+				Type:   "https://example.com/probs/out-of-credit",
+				Title:  "You do not have enough credit.",
+				Detail: "Your current balance is 30, but that costs <50>.",
+				Extensions: map[string]interface{}{
+					"balance":  30,
+					"accounts": []string{"/account/12345", "/account/67890"},
+					"limits": map[string]interface{}{
+						"daily": 100,
+					},
+					"invalid name": true,
+				},
+			},
+		}
+	}))
+	mux.HandleFunc("/error", h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		return &httphandler.HandlerError{
+			StatusCode:  http.StatusNotFound,
+			PublicError: errors.New("user & group not found"),
+		}
+	}))
+
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	t.Run("problem", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(hit.JoinURL(s.URL, "problem")),
+			hit.Send().Headers("Accept").Add("application/problem+xml"),
+			hit.Expect().Status().Equal(http.StatusForbidden),
+			hit.Expect().Headers("Content-Type").Equal("application/problem+xml"),
+			hit.Expect().Body().String().Equal(`<?xml version="1.0" encoding="UTF-8"?>`+"\n"+
+				`<problem xmlns="urn:ietf:rfc:7807">`+
+				`<type>https://example.com/probs/out-of-credit</type>`+
+				`<title>You do not have enough credit.</title>`+
+				`<status>403</status>`+
+				`<detail>Your current balance is 30, but that costs &lt;50&gt;.</detail>`+
+				`<accounts><i>/account/12345</i><i>/account/67890</i></accounts>`+
+				`<balance>30</balance>`+
+				`<limits><daily>100</daily></limits>`+
+				`<requestUUID>0123456789</requestUUID>`+
+				`</problem>`),
+		)
+	})
+
+	t.Run("xml with problem details", func(t *testing.T) {
+		h.SetProblemDetails(true)
+		defer h.SetProblemDetails(false)
+		hit.Test(t,
+			hit.Get(hit.JoinURL(s.URL, "error")),
+			hit.Send().Headers("Accept").Add("text/xml"),
+			hit.Expect().Status().Equal(http.StatusNotFound),
+			hit.Expect().Headers("Content-Type").Equal("application/problem+xml"),
+			hit.Expect().Body().String().Contains(`<title>Not Found</title><status>404</status>`+
+				`<detail>user &amp; group not found</detail>`),
+		)
+	})
+}
+
+func TestProblemDetailsEncoders(t *testing.T) {
+	h := httphandler.New(nil)
+	require.NotContains(t, h.Encoders().MediaTypes(), "application/problem+json")
+	require.NotContains(t, h.Encoders().MediaTypes(), "application/problem+xml")
+
+	h.SetProblemDetails(true)
+	require.Contains(t, h.Encoders().MediaTypes(), "application/problem+json")
+	require.Contains(t, h.Encoders().MediaTypes(), "application/problem+xml")
+
+	h = httphandler.New(&httphandler.Options{ProblemDetails: true})
+	require.Contains(t, h.Encoders().MediaTypes(), "application/problem+json")
+	require.Contains(t, h.Encoders().MediaTypes(), "application/problem+xml")
+
+	t.Run("custom encoders are kept", func(t *testing.T) {
+		s := httptest.NewServer(httphandler.New(&httphandler.Options{
+			Encoders: map[string]httphandler.EncodeFunc{
+				"application/json":         stringEncoder("json"),
+				"application/problem+json": stringEncoder("problem"),
+			},
+			ProblemDetails: true,
+		}).HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+			return &httphandler.HandlerError{}
+		}))
+		defer s.Close()
+		hit.Test(t,
+			hit.Get(s.URL),
+			hit.Send().Headers("Accept").Add("application/json"),
+			hit.Expect().Headers("Content-Type").Equal("application/problem+json"),
+			hit.Expect().Body().String().Equal("problem"),
+		)
+	})
+}
@@ -0,0 +1,231 @@
+package httphandler
+
+import (
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ValueEncodeFunc is the encode function that will be called to encode the value of a successful response in the
+// desired format, see Handler.Respond.
+type ValueEncodeFunc func(w http.ResponseWriter, r *http.Request, v interface{}) error
+
+// ValueEncoder can be implemented by an Encoder that is also able to encode the values of successful responses.
+// Registering such an Encoder with the EncoderRegistry sets its EncodeValue function in the ValueEncoders of the
+// Options for all its media types.
+type ValueEncoder interface {
+	// EncodeValue encodes the value of a successful response.
+	EncodeValue(w http.ResponseWriter, r *http.Request, v interface{}) error
+}
+
+// Respond sends v with the specified status code, the content type is negotiated the same way as for errors: the
+// FormatSources are consulted first, then the clients Accept header is matched against the ValueEncoders of the
+// Options (in the order of the EncoderRegistry) and if the client did not send an Accept header the content type of
+// the FallbackEncoderFunc is used.
+// The value is encoded into a buffer before anything is written, so if the encoding fails (or the Accept header
+// cannot be satisfied in strict negotiation mode) the error is sent instead and returned as HandlerError. The returned
+// HandlerError can be returned from the handler func, HandleFunc will log it without sending a second response.
+//
+// Example:
+//
+//	http.HandleFunc("/user", handler.HandleFunc(func(w http.ResponseWriter, r *http.Request) *HandlerError {
+//	    user, err := loadUser(r)
+//	    if err != nil {
+//	        return &HandlerError{StatusCode: http.StatusNotFound, PublicError: errors.New("user not found")}
+//	    }
+//	    return handler.Respond(w, r, http.StatusOK, user)
+//	}))
+func (h *Handler) Respond(w http.ResponseWriter, r *http.Request, status int, v interface{}) *HandlerError {
+	requestUUID := GetRequestUUID(r)
+	if requestUUID == "" {
+		requestUUID = h.options.RequestUUIDFunc()
+	}
+
+	f, contentType, vary, acceptable := getValueEncoder(h.options, r)
+	addVary(w.Header(), vary...)
+	if !acceptable {
+		err := &HandlerError{
+			StatusCode: http.StatusNotAcceptable,
+			PublicError: &NotAcceptableError{
+				AvailableMediaTypes: valueContentTypes(h.options),
+			},
+		}
+		errorToSend := &WireError{StatusCode: err.StatusCode, Error: err.PublicError, RequestUUID: requestUUID}
+		f, contentType := h.options.NotAcceptableEncoderFunc()
+		h.encode(w, r, err, errorToSend, f, contentType, false)
+		return err
+	}
+	if f == nil {
+		return h.sendRespondError(w, r, requestUUID, errors.New("no value encoder available"))
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+	bw := &bufferedResponseWriter{buf: buf}
+	if err := f(bw, r, v); err != nil {
+		return h.sendRespondError(w, r, requestUUID, errors.Wrapf(err, "unable to encode %q", contentType))
+	}
+
+	for key, values := range bw.header {
+		w.Header()[key] = values
+	}
+	if w.Header().Get("Content-Type") == "" {
+		if charset := h.options.encoderRegistry().charset(contentType); charset != "" {
+			contentType += "; charset=" + charset
+		}
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(status)
+	_, _ = buf.WriteTo(w)
+	return nil
+}
+
+// sendRespondError sends the error that occurred in Respond and returns it as HandlerError.
+func (h *Handler) sendRespondError(w http.ResponseWriter, r *http.Request, requestUUID string, internalError error) *HandlerError {
+	err := &HandlerError{
+		StatusCode:    http.StatusInternalServerError,
+		PublicError:   errors.New("unable to encode response"),
+		InternalError: internalError,
+	}
+	h.sendError(err, requestUUID, w, r, false)
+	return err
+}
+
+// getValueEncoder returns the value encoder and content type for the request, see Handler.Respond.
+// It also returns the request headers that have been used to select the content type, if the clients Accept header
+// cannot be satisfied in strict negotiation mode acceptable is false.
+func getValueEncoder(options *Options, r *http.Request) (f ValueEncodeFunc, contentType string, vary []string, acceptable bool) {
+	for _, source := range options.FormatSources {
+		if v, ok := source.(VaryFormatSource); ok {
+			vary = append(vary, v.VaryHeaders()...)
+		}
+		if f, contentType := resolveValueFormat(options, source.Format(r)); f != nil {
+			return f, contentType, vary, true
+		}
+	}
+
+	vary = append(vary, "Accept")
+	if ranges := parseAccept(r.Header.Values("Accept")); len(ranges) > 0 {
+		if contentType, ok := negotiate(ranges, valueContentTypes(options)); ok {
+			return options.ValueEncoders[contentType], contentType, vary, true
+		}
+		if options.StrictNegotiation {
+			return nil, "", vary, false
+		}
+	}
+
+	// use the content type of the fallback encoder, or the most preferred value encoder
+	if options.FallbackEncoderFunc != nil {
+		_, contentType = options.FallbackEncoderFunc()
+		contentType = strings.ToLower(contentType)
+		if f, ok := options.ValueEncoders[contentType]; ok {
+			return f, contentType, vary, true
+		}
+	}
+	if contentTypes := valueContentTypes(options); len(contentTypes) > 0 {
+		return options.ValueEncoders[contentTypes[0]], contentTypes[0], vary, true
+	}
+	return nil, "", vary, true
+}
+
+// resolveValueFormat returns the value encoder and content type for the specified format, the format must either be
+// one of the FormatAliases or one of the content types of the ValueEncoders (see resolveFormat).
+func resolveValueFormat(options *Options, format string) (f ValueEncodeFunc, contentType string) {
+	contentType, _ = formatContentType(options, format)
+	if f, ok := options.ValueEncoders[contentType]; ok && contentType != "" {
+		return f, contentType
+	}
+	return nil, ""
+}
+
+// valueContentTypes returns the content types of the ValueEncoders in the order of the EncoderRegistry, content
+// types that have no encoder in the registry follow in a stable order.
+func valueContentTypes(options *Options) []string {
+	contentTypes := make([]string, 0, len(options.ValueEncoders))
+	seen := make(map[string]struct{}, len(options.ValueEncoders))
+	for _, contentType := range options.encoderRegistry().orderedMediaTypes() {
+		if _, ok := options.ValueEncoders[contentType]; ok {
+			contentTypes = append(contentTypes, contentType)
+			seen[contentType] = struct{}{}
+		}
+	}
+	var rest []string
+	for contentType := range options.ValueEncoders {
+		if _, ok := seen[strings.ToLower(contentType)]; !ok {
+			rest = append(rest, strings.ToLower(contentType))
+		}
+	}
+	sort.Strings(rest)
+	return append(contentTypes, rest...)
+}
+
+// Respond sends v with the specified status code using the DefaultHandler.
+// See also Handler.Respond.
+func Respond(w http.ResponseWriter, r *http.Request, status int, v interface{}) *HandlerError {
+	return DefaultHandler.Respond(w, r, status, v)
+}
+
+func defaultValueEncoders() map[string]ValueEncodeFunc {
+	return map[string]ValueEncodeFunc{
+		"application/json": DefaultJSONValueEncoder(),
+		"application/xml":  DefaultXMLValueEncoder(),
+		"text/plain":       DefaultTextValueEncoder(),
+		"text/xml":         DefaultXMLValueEncoder(),
+	}
+}
+
+// DefaultJSONValueEncoder implements the default JSON value encoder, it marshals the value using encoding/json.
+func DefaultJSONValueEncoder() ValueEncodeFunc {
+	return func(w http.ResponseWriter, r *http.Request, v interface{}) error {
+		return json.NewEncoder(w).Encode(v)
+	}
+}
+
+// DefaultXMLValueEncoder implements the default XML value encoder, it marshals the value using encoding/xml and
+// prepends the XML header.
+func DefaultXMLValueEncoder() ValueEncodeFunc {
+	return func(w http.ResponseWriter, r *http.Request, v interface{}) error {
+		if _, err := io.WriteString(w, xml.Header); err != nil {
+			return err
+		}
+		return xml.NewEncoder(w).Encode(v)
+	}
+}
+
+// DefaultTextValueEncoder implements the default text/plain value encoder.
+// Strings and byte slices are written as they are, values that implement encoding.TextMarshaler are marshaled and
+// every other value is formatted using fmt.Sprint. The Content-Type is sent with charset=utf-8.
+func DefaultTextValueEncoder() ValueEncodeFunc {
+	return func(w http.ResponseWriter, r *http.Request, v interface{}) error {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		switch value := v.(type) {
+		case nil:
+			return nil
+		case string:
+			_, err := io.WriteString(w, value)
+			return err
+		case []byte:
+			_, err := w.Write(value)
+			return err
+		case encoding.TextMarshaler:
+			text, err := value.MarshalText()
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(text)
+			return err
+		default:
+			_, err := fmt.Fprint(w, value)
+			return err
+		}
+	}
+}
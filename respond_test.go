@@ -0,0 +1,158 @@
+package httphandler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Eun/go-hit"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/talon-one/go-httphandler"
+)
+
+type respondUser struct {
+	Name string `json:"name" xml:"Name"`
+	Age  int    `json:"age" xml:"Age"`
+}
+
+func TestRespond(t *testing.T) {
+	var loggedErrors []error
+	h := httphandler.New(&httphandler.Options{
+		LogFunc: func(_ *http.Request, _, internalError, _ error, _ int, _ string) {
+			loggedErrors = append(loggedErrors, internalError)
+		},
+		FormatSources: []httphandler.FormatSource{httphandler.FormatFromQuery("format")},
+	})
+	require.NoError(t, h.SetRequestUUIDFunc(func() string {
+		return "0123456789"
+	}))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/user", h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		return h.Respond(w, r, http.StatusCreated, &respondUser{Name: "Joe", Age: 42})
+	}))
+	mux.HandleFunc("/faulty", h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		return h.Respond(w, r, http.StatusOK, map[string]interface{}{"channel": make(chan int)})
+	}))
+
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	t.Run("no accept header", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(hit.JoinURL(s.URL, "user")),
+			hit.Expect().Status().Equal(http.StatusCreated),
+			hit.Expect().Headers("Content-Type").Equal("application/json"),
+			hit.Expect().Headers("Vary").Equal("Accept"),
+			hit.Expect().Body().JSON().Equal(map[string]interface{}{"name": "Joe", "age": 42}),
+		)
+	})
+
+	t.Run("xml", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(hit.JoinURL(s.URL, "user")),
+			hit.Send().Headers("Accept").Add("text/xml"),
+			hit.Expect().Status().Equal(http.StatusCreated),
+			hit.Expect().Headers("Content-Type").Equal("text/xml"),
+			hit.Expect().Body().String().Equal(
+				"<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<respondUser><Name>Joe</Name><Age>42</Age></respondUser>",
+			),
+		)
+	})
+
+	t.Run("text", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(hit.JoinURL(s.URL, "user")),
+			hit.Send().Headers("Accept").Add("text/plain"),
+			hit.Expect().Status().Equal(http.StatusCreated),
+			hit.Expect().Headers("Content-Type").Equal("text/plain; charset=utf-8"),
+			hit.Expect().Body().String().Equal("&{Joe 42}"),
+		)
+	})
+
+	t.Run("format override", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(hit.JoinURL(s.URL, "user?format=xml")),
+			hit.Send().Headers("Accept").Add("application/json"),
+			hit.Expect().Status().Equal(http.StatusCreated),
+			hit.Expect().Headers("Content-Type").Equal("application/xml"),
+		)
+	})
+
+	t.Run("unacceptable", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(hit.JoinURL(s.URL, "user")),
+			hit.Send().Headers("Accept").Add("image/png"),
+			hit.Expect().Status().Equal(http.StatusCreated),
+			hit.Expect().Headers("Content-Type").Equal("application/json"),
+		)
+	})
+
+	t.Run("encoding error", func(t *testing.T) {
+		loggedErrors = nil
+		hit.Test(t,
+			hit.Get(hit.JoinURL(s.URL, "faulty")),
+			hit.Send().Headers("Accept").Add("application/json"),
+			hit.Expect().Status().Equal(http.StatusInternalServerError),
+			hit.Expect().Headers("Content-Type").Equal("application/json"),
+			hit.Expect().Body().JSON().Equal(map[string]interface{}{
+				"StatusCode":  http.StatusInternalServerError,
+				"Error":       "unable to encode response",
+				"RequestUUID": "0123456789",
+			}),
+		)
+		require.Len(t, loggedErrors, 1)
+		require.Contains(t, loggedErrors[0].Error(), `unable to encode "application/json"`)
+	})
+}
+
+func TestRespondStrictNegotiation(t *testing.T) {
+	h := httphandler.New(&httphandler.Options{
+		StrictNegotiation: true,
+	})
+	require.NoError(t, h.SetValueEncoder("application/vnd.users+json", httphandler.DefaultJSONValueEncoder()))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/user", h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		return h.Respond(w, r, http.StatusOK, &respondUser{Name: "Joe", Age: 42})
+	}))
+
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	t.Run("custom value encoder", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(hit.JoinURL(s.URL, "user")),
+			hit.Send().Headers("Accept").Add("application/vnd.users+json"),
+			hit.Expect().Status().Equal(http.StatusOK),
+			hit.Expect().Headers("Content-Type").Equal("application/vnd.users+json"),
+			hit.Expect().Body().JSON().Equal(map[string]interface{}{"name": "Joe", "age": 42}),
+		)
+	})
+
+	t.Run("not acceptable", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(hit.JoinURL(s.URL, "user")),
+			hit.Send().Headers("Accept").Add("image/png"),
+			hit.Expect().Status().Equal(http.StatusNotAcceptable),
+			hit.Expect().Headers("Content-Type").Equal("text/plain; charset=utf-8"),
+			hit.Expect().Body().String().Contains(
+				"available media types are: application/json, application/xml, text/xml, text/plain, application/vnd.users+json",
+			),
+		)
+	})
+}
+
+func TestPackageRespond(t *testing.T) {
+	s := httptest.NewServer(httphandler.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		return httphandler.Respond(w, r, http.StatusOK, "hello")
+	}))
+	defer s.Close()
+
+	hit.Test(t,
+		hit.Get(s.URL),
+		hit.Send().Headers("Accept").Add("text/plain"),
+		hit.Expect().Status().Equal(http.StatusOK),
+		hit.Expect().Body().String().Equal("hello"),
+	)
+}
@@ -0,0 +1,395 @@
+package httphandler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// RPCCode is a canonical gRPC status code (google.rpc.Code).
+type RPCCode int32
+
+// The canonical gRPC status codes.
+const (
+	RPCCodeOK                 RPCCode = 0
+	RPCCodeCanceled           RPCCode = 1
+	RPCCodeUnknown            RPCCode = 2
+	RPCCodeInvalidArgument    RPCCode = 3
+	RPCCodeDeadlineExceeded   RPCCode = 4
+	RPCCodeNotFound           RPCCode = 5
+	RPCCodeAlreadyExists      RPCCode = 6
+	RPCCodePermissionDenied   RPCCode = 7
+	RPCCodeResourceExhausted  RPCCode = 8
+	RPCCodeFailedPrecondition RPCCode = 9
+	RPCCodeAborted            RPCCode = 10
+	RPCCodeOutOfRange         RPCCode = 11
+	RPCCodeUnimplemented      RPCCode = 12
+	RPCCodeInternal           RPCCode = 13
+	RPCCodeUnavailable        RPCCode = 14
+	RPCCodeDataLoss           RPCCode = 15
+	RPCCodeUnauthenticated    RPCCode = 16
+)
+
+// statusClientClosedRequest is the (non standard) status code for requests the client canceled.
+const statusClientClosedRequest = 499
+
+// statusText returns the text for the http status code like http.StatusText, it also knows the text of
+// statusClientClosedRequest.
+func statusText(statusCode int) string {
+	if statusCode == statusClientClosedRequest {
+		return "Client Closed Request"
+	}
+	return http.StatusText(statusCode)
+}
+
+// RPCCodeFromHTTPStatus returns the canonical gRPC status code for the http status code, the mapping follows the
+// Google API design guide. Status codes without a mapping result in RPCCodeOK for 2xx and RPCCodeUnknown otherwise.
+func RPCCodeFromHTTPStatus(statusCode int) RPCCode {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return RPCCodeInvalidArgument
+	case http.StatusUnauthorized:
+		return RPCCodeUnauthenticated
+	case http.StatusForbidden:
+		return RPCCodePermissionDenied
+	case http.StatusNotFound:
+		return RPCCodeNotFound
+	case http.StatusConflict:
+		return RPCCodeAborted
+	case http.StatusPreconditionFailed:
+		return RPCCodeFailedPrecondition
+	case http.StatusRequestedRangeNotSatisfiable:
+		return RPCCodeOutOfRange
+	case http.StatusTooManyRequests:
+		return RPCCodeResourceExhausted
+	case statusClientClosedRequest:
+		return RPCCodeCanceled
+	case http.StatusInternalServerError:
+		return RPCCodeInternal
+	case http.StatusNotImplemented:
+		return RPCCodeUnimplemented
+	case http.StatusServiceUnavailable:
+		return RPCCodeUnavailable
+	case http.StatusGatewayTimeout:
+		return RPCCodeDeadlineExceeded
+	}
+	if statusCode >= 200 && statusCode < 300 {
+		return RPCCodeOK
+	}
+	return RPCCodeUnknown
+}
+
+// RPCStatus is a PublicError that is rendered as google.rpc.Status by the RPC status encoders (e.g.
+// RPCStatusProtoEncoder), other encoders render the Message.
+//
+// Example:
+//
+//	return &HandlerError{
+//	    StatusCode: http.StatusBadRequest,
+//	    PublicError: &RPCStatus{
+//	        Message: "invalid user",
+//	        Details: []RPCStatusDetail{
+//	            &RPCBadRequest{FieldViolations: []RPCFieldViolation{
+//	                {Field: "email", Description: "email is not valid"},
+//	            }},
+//	        },
+//	    },
+//	}
+type RPCStatus struct {
+	// Code is the gRPC status code. If not specified the code will be derived from the http status code, see
+	// RPCCodeFromHTTPStatus.
+	Code RPCCode
+	// Message is the error message for the client.
+	Message string
+	// Details carry additional information about the error.
+	Details []RPCStatusDetail
+}
+
+func (s *RPCStatus) Error() string {
+	return s.Message
+}
+
+// RPCStatusDetail is a message that can be used in the details of a google.rpc.Status.
+// It is encoded as google.protobuf.Any, the JSON mapping is the JSON encoding of the detail with an additional
+// "@type" member.
+type RPCStatusDetail interface {
+	// TypeURL returns the type url of the message (e.g. "type.googleapis.com/google.rpc.ErrorInfo").
+	TypeURL() string
+	// MarshalProto returns the message in the protobuf wire format.
+	MarshalProto() ([]byte, error)
+}
+
+// RPCErrorInfo describes the cause of the error with structured details (google.rpc.ErrorInfo).
+type RPCErrorInfo struct {
+	// Reason is the reason of the error in UPPER_SNAKE_CASE (e.g. "API_DISABLED").
+	Reason string `json:"reason,omitempty"`
+	// Domain is the logical grouping to which the Reason belongs (e.g. "example.com").
+	Domain string `json:"domain,omitempty"`
+	// Metadata is additional structured information about the error.
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// TypeURL returns the type url of google.rpc.ErrorInfo.
+func (*RPCErrorInfo) TypeURL() string {
+	return "type.googleapis.com/google.rpc.ErrorInfo"
+}
+
+// MarshalProto returns the RPCErrorInfo in the protobuf wire format.
+func (e *RPCErrorInfo) MarshalProto() ([]byte, error) {
+	var buf []byte
+	buf = appendProtoString(buf, 1, e.Reason)
+	buf = appendProtoString(buf, 2, e.Domain)
+	keys := make([]string, 0, len(e.Metadata))
+	for key := range e.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		var entry []byte
+		entry = appendProtoString(entry, 1, key)
+		entry = appendProtoString(entry, 2, e.Metadata[key])
+		buf = appendProtoBytes(buf, 3, entry) //nolint:gomnd // field number of metadata
+	}
+	return buf, nil
+}
+
+// RPCBadRequest describes violations in a client request (google.rpc.BadRequest).
+type RPCBadRequest struct {
+	// FieldViolations are the violations in the request.
+	FieldViolations []RPCFieldViolation `json:"fieldViolations,omitempty"`
+}
+
+// RPCFieldViolation is a single field violation of an RPCBadRequest.
+type RPCFieldViolation struct {
+	// Field is the path to the field (e.g. "user.email").
+	Field string `json:"field,omitempty"`
+	// Description describes why the field is bad.
+	Description string `json:"description,omitempty"`
+}
+
+// TypeURL returns the type url of google.rpc.BadRequest.
+func (*RPCBadRequest) TypeURL() string {
+	return "type.googleapis.com/google.rpc.BadRequest"
+}
+
+// MarshalProto returns the RPCBadRequest in the protobuf wire format.
+func (b *RPCBadRequest) MarshalProto() ([]byte, error) {
+	var buf []byte
+	for _, violation := range b.FieldViolations {
+		var v []byte
+		v = appendProtoString(v, 1, violation.Field)
+		v = appendProtoString(v, 2, violation.Description)
+		buf = appendProtoBytes(buf, 1, v)
+	}
+	return buf, nil
+}
+
+// RPCRetryInfo describes when the client can retry the request (google.rpc.RetryInfo).
+type RPCRetryInfo struct {
+	// RetryDelay is the duration the client should wait until the next retry.
+	RetryDelay time.Duration
+}
+
+// TypeURL returns the type url of google.rpc.RetryInfo.
+func (*RPCRetryInfo) TypeURL() string {
+	return "type.googleapis.com/google.rpc.RetryInfo"
+}
+
+// MarshalProto returns the RPCRetryInfo in the protobuf wire format.
+func (r *RPCRetryInfo) MarshalProto() ([]byte, error) {
+	if r.RetryDelay == 0 {
+		return nil, nil
+	}
+	// google.protobuf.Duration
+	var d []byte
+	d = appendProtoVarint(d, 1, uint64(r.RetryDelay/time.Second))
+	d = appendProtoVarint(d, 2, uint64(r.RetryDelay%time.Second))
+	return appendProtoBytes(nil, 1, d), nil
+}
+
+// MarshalJSON returns the JSON mapping of the RPCRetryInfo, the delay is encoded as seconds (e.g. "1.500s").
+func (r *RPCRetryInfo) MarshalJSON() ([]byte, error) {
+	if r.RetryDelay == 0 {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(map[string]string{"retryDelay": formatProtoDuration(r.RetryDelay)})
+}
+
+// formatProtoDuration formats the duration as defined by the JSON mapping of google.protobuf.Duration: seconds with
+// 0, 3, 6 or 9 fractional digits and the suffix "s".
+func formatProtoDuration(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+	seconds, nanos := int64(d/time.Second), int64(d%time.Second)
+	s := sign + strconv.FormatInt(seconds, 10)
+	switch {
+	case nanos == 0:
+	case nanos%int64(time.Millisecond) == 0:
+		s += fmt.Sprintf(".%03d", nanos/int64(time.Millisecond))
+	case nanos%int64(time.Microsecond) == 0:
+		s += fmt.Sprintf(".%06d", nanos/int64(time.Microsecond))
+	default:
+		s += fmt.Sprintf(".%09d", nanos)
+	}
+	return s + "s"
+}
+
+// RPCRequestInfo contains the request id of the failed request (google.rpc.RequestInfo).
+// The RPC status encoders add it with the RequestUUID of the WireError.
+type RPCRequestInfo struct {
+	// RequestID is the id of the request.
+	RequestID string `json:"requestId,omitempty"`
+	// ServingData is any data that was used to serve the request.
+	ServingData string `json:"servingData,omitempty"`
+}
+
+// TypeURL returns the type url of google.rpc.RequestInfo.
+func (*RPCRequestInfo) TypeURL() string {
+	return "type.googleapis.com/google.rpc.RequestInfo"
+}
+
+// MarshalProto returns the RPCRequestInfo in the protobuf wire format.
+func (r *RPCRequestInfo) MarshalProto() ([]byte, error) {
+	var buf []byte
+	buf = appendProtoString(buf, 1, r.RequestID)
+	buf = appendProtoString(buf, 2, r.ServingData)
+	return buf, nil
+}
+
+// rpcStatus returns the RPCStatus for the WireError.
+// If the Error of the WireError is not a *RPCStatus a status is created that uses the error message as message, the
+// RequestUUID is added as RPCRequestInfo.
+func rpcStatus(e *WireError) *RPCStatus {
+	var status RPCStatus
+	var s *RPCStatus
+	if errors.As(e.Error, &s) {
+		status = *s
+	} else if e.Error != nil {
+		status.Message = e.Error.Error()
+	}
+	if status.Code == RPCCodeOK {
+		status.Code = RPCCodeFromHTTPStatus(e.StatusCode)
+	}
+	if e.RequestUUID != "" {
+		status.Details = append(status.Details[:len(status.Details):len(status.Details)], &RPCRequestInfo{
+			RequestID: e.RequestUUID,
+		})
+	}
+	return &status
+}
+
+// RPCStatusProtoEncoder implements an encoder that renders the WireError as google.rpc.Status in the protobuf wire
+// format. It should be registered for "application/x-protobuf" (or "application/protobuf").
+// If the PublicError is a *RPCStatus its code, message and details are used, otherwise the code is derived from the
+// status code and the error message is used as message. The RequestUUID is added as RPCRequestInfo detail.
+//
+// Example:
+//
+//	handler.SetEncoder("application/x-protobuf", RPCStatusProtoEncoder())
+func RPCStatusProtoEncoder() EncodeFunc {
+	return func(w http.ResponseWriter, r *http.Request, e *WireError) error {
+		status := rpcStatus(e)
+
+		var buf []byte
+		buf = appendProtoVarint(buf, 1, uint64(status.Code))
+		buf = appendProtoString(buf, 2, status.Message)
+		for _, detail := range status.Details {
+			value, err := detail.MarshalProto()
+			if err != nil {
+				return errors.Wrapf(err, "unable to encode detail %q", detail.TypeURL())
+			}
+			// google.protobuf.Any
+			var anyValue []byte
+			anyValue = appendProtoString(anyValue, 1, detail.TypeURL())
+			anyValue = appendProtoBytes(anyValue, 2, value)
+			buf = appendProtoBytes(buf, 3, anyValue) //nolint:gomnd // field number of details
+		}
+
+		_, err := w.Write(buf)
+		return err
+	}
+}
+
+// RPCStatusJSONEncoder implements an encoder that renders the WireError as the JSON mapping of google.rpc.Status
+// (e.g. {"code":5,"message":"user not found","details":[...]}), like gRPC-gateway does.
+// The status is created the same way RPCStatusProtoEncoder does.
+//
+// Example:
+//
+//	handler.SetEncoder("application/json", RPCStatusJSONEncoder())
+func RPCStatusJSONEncoder() EncodeFunc {
+	return func(w http.ResponseWriter, r *http.Request, e *WireError) error {
+		status := rpcStatus(e)
+
+		details := make([]map[string]interface{}, 0, len(status.Details))
+		for _, detail := range status.Details {
+			buf, err := json.Marshal(detail)
+			if err != nil {
+				return errors.Wrapf(err, "unable to encode detail %q", detail.TypeURL())
+			}
+			members := make(map[string]interface{})
+			if err := json.Unmarshal(buf, &members); err != nil {
+				return errors.Wrapf(err, "detail %q is not a JSON object", detail.TypeURL())
+			}
+			members["@type"] = detail.TypeURL()
+			details = append(details, members)
+		}
+
+		return json.NewEncoder(w).Encode(struct {
+			Code    RPCCode                  `json:"code"`
+			Message string                   `json:"message,omitempty"`
+			Details []map[string]interface{} `json:"details,omitempty"`
+		}{
+			Code:    status.Code,
+			Message: status.Message,
+			Details: details,
+		})
+	}
+}
+
+// protobuf wire types.
+const (
+	protoWireVarint = 0
+	protoWireBytes  = 2
+)
+
+// appendProtoVarint appends a varint field, zero values are left out (proto3 semantics).
+func appendProtoVarint(buf []byte, field int, v uint64) []byte {
+	if v == 0 {
+		return buf
+	}
+	buf = appendVarint(buf, uint64(field)<<3|protoWireVarint)
+	return appendVarint(buf, v)
+}
+
+// appendProtoString appends a string field, empty strings are left out (proto3 semantics).
+func appendProtoString(buf []byte, field int, v string) []byte {
+	if v == "" {
+		return buf
+	}
+	return appendProtoBytes(buf, field, []byte(v))
+}
+
+// appendProtoBytes appends a length delimited field (bytes or an embedded message).
+func appendProtoBytes(buf []byte, field int, v []byte) []byte {
+	buf = appendVarint(buf, uint64(field)<<3|protoWireBytes)
+	buf = appendVarint(buf, uint64(len(v)))
+	return append(buf, v...)
+}
+
+// appendVarint appends v as base 128 varint.
+func appendVarint(buf []byte, v uint64) []byte {
+	for v >= 0x80 {
+		buf = append(buf, byte(v)|0x80) //nolint:gomnd // the high bit marks that more bytes follow
+		v >>= 7                         //nolint:gomnd // every byte holds 7 bits
+	}
+	return append(buf, byte(v))
+}
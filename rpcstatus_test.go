@@ -0,0 +1,141 @@
+package httphandler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Eun/go-hit"
+
+	"github.com/pkg/errors"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/talon-one/go-httphandler"
+)
+
+func TestRPCCodeFromHTTPStatus(t *testing.T) {
+	tests := []struct {
+		statusCode int
+		code       httphandler.RPCCode
+	}{
+		{http.StatusOK, httphandler.RPCCodeOK},
+		{http.StatusBadRequest, httphandler.RPCCodeInvalidArgument},
+		{http.StatusUnauthorized, httphandler.RPCCodeUnauthenticated},
+		{http.StatusForbidden, httphandler.RPCCodePermissionDenied},
+		{http.StatusNotFound, httphandler.RPCCodeNotFound},
+		{http.StatusConflict, httphandler.RPCCodeAborted},
+		{http.StatusTooManyRequests, httphandler.RPCCodeResourceExhausted},
+		{499, httphandler.RPCCodeCanceled},
+		{http.StatusInternalServerError, httphandler.RPCCodeInternal},
+		{http.StatusServiceUnavailable, httphandler.RPCCodeUnavailable},
+		{http.StatusGatewayTimeout, httphandler.RPCCodeDeadlineExceeded},
+		{http.StatusTeapot, httphandler.RPCCodeUnknown},
+	}
+	for _, test := range tests {
+		require.Equal(t, test.code, httphandler.RPCCodeFromHTTPStatus(test.statusCode), "status code %d", test.statusCode)
+	}
+}
+
+func TestRPCStatusProtoEncoder(t *testing.T) {
+	encode := func(statusCode int, err error, requestUUID string) string {
+		w := httptest.NewRecorder()
+		require.NoError(t, httphandler.RPCStatusProtoEncoder()(w, nil, &httphandler.WireError{
+			StatusCode:  statusCode,
+			Error:       err,
+			RequestUUID: requestUUID,
+		}))
+		return w.Body.String()
+	}
+
+	t.Run("plain error", func(t *testing.T) {
+		require.Equal(t,
+			"\x08\x05"+
+				"\x12\x0euser not found"+
+				"\x1a\x31\x0a\x2atype.googleapis.com/google.rpc.RequestInfo\x12\x03\x0a\x011",
+			encode(http.StatusNotFound, errors.New("user not found"), "1"),
+		)
+	})
+
+	t.Run("details", func(t *testing.T) {
+		require.Equal(t,
+			"\x08\x03"+
+				"\x12\x07invalid"+
+				"\x1a\x3a\x0a\x28type.googleapis.com/google.rpc.ErrorInfo\x12\x0e\x0a\x01R\x12\x01d\x1a\x06\x0a\x01k\x12\x01v"+
+				"\x1a\x35\x0a\x29type.googleapis.com/google.rpc.BadRequest\x12\x08\x0a\x06\x0a\x01f\x12\x01x"+
+				"\x1a\x36\x0a\x28type.googleapis.com/google.rpc.RetryInfo\x12\x0a\x0a\x08\x08\x01\x10\x80\xca\xb5\xee\x01",
+			encode(http.StatusBadRequest, &httphandler.RPCStatus{
+				Message: "invalid",
+				Details: []httphandler.RPCStatusDetail{
+					&httphandler.RPCErrorInfo{Reason: "R", Domain: "d", Metadata: map[string]string{"k": "v"}},
+					&httphandler.RPCBadRequest{FieldViolations: []httphandler.RPCFieldViolation{{Field: "f", Description: "x"}}},
+					&httphandler.RPCRetryInfo{RetryDelay: 1500 * time.Millisecond},
+				},
+			}, ""),
+		)
+	})
+
+	t.Run("explicit code", func(t *testing.T) {
+		require.Equal(t, "\x08\x06\x12\x06exists", encode(http.StatusConflict, errors.Wrap(&httphandler.RPCStatus{
+			Code:    httphandler.RPCCodeAlreadyExists,
+			Message: "exists",
+		}, "wrapped"), ""))
+	})
+}
+
+func TestRPCStatusJSONEncoder(t *testing.T) {
+	h := httphandler.New(nil)
+	require.NoError(t, h.SetRequestUUIDFunc(func() string {
+		return "0123456789"
+	}))
+	require.NoError(t, h.SetEncoder("application/json", httphandler.RPCStatusJSONEncoder()))
+	require.NoError(t, h.SetEncoder("application/x-protobuf", httphandler.RPCStatusProtoEncoder()))
+
+	s := httptest.NewServer(h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		return &httphandler.HandlerError{
+			StatusCode: http.StatusTooManyRequests,
+			PublicError: &httphandler.RPCStatus{
+				Message: "quota exceeded",
+				Details: []httphandler.RPCStatusDetail{
+					&httphandler.RPCErrorInfo{Reason: "RATE_LIMIT_EXCEEDED", Domain: "example.com"},
+					&httphandler.RPCRetryInfo{RetryDelay: 2 * time.Second},
+				},
+			},
+		}
+	}))
+	defer s.Close()
+
+	hit.Test(t,
+		hit.Get(s.URL),
+		hit.Send().Headers("Accept").Add("application/json"),
+		hit.Expect().Status().Equal(http.StatusTooManyRequests),
+		hit.Expect().Headers("Content-Type").Equal("application/json"),
+		hit.Expect().Body().JSON().Equal(map[string]interface{}{
+			"code":    8,
+			"message": "quota exceeded",
+			"details": []interface{}{
+				map[string]interface{}{
+					"@type":  "type.googleapis.com/google.rpc.ErrorInfo",
+					"reason": "RATE_LIMIT_EXCEEDED",
+					"domain": "example.com",
+				},
+				map[string]interface{}{
+					"@type":      "type.googleapis.com/google.rpc.RetryInfo",
+					"retryDelay": "2s",
+				},
+				map[string]interface{}{
+					"@type":     "type.googleapis.com/google.rpc.RequestInfo",
+					"requestId": "0123456789",
+				},
+			},
+		}),
+	)
+
+	hit.Test(t,
+		hit.Get(s.URL),
+		hit.Send().Headers("Accept").Add("application/x-protobuf"),
+		hit.Expect().Headers("Content-Type").Equal("application/x-protobuf"),
+		hit.Expect().Body().String().Contains("quota exceeded"),
+	)
+}
@@ -0,0 +1,47 @@
+package httphandler
+
+import (
+	"bytes"
+	"net/http"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultTextLayout is the layout of the DefaultTextEncoder.
+const DefaultTextLayout = "{{.StatusCode}} {{.StatusText}}: {{.Message}} (RequestUUID: {{.RequestUUID}})\n"
+
+// DefaultTextEncoder implements the default text/plain encoder that will be used.
+// It renders the WireError in one line, see DefaultTextLayout. The default Options register it with the charset utf-8.
+func DefaultTextEncoder() EncodeFunc {
+	return textEncoder(template.Must(template.New("text").Parse(DefaultTextLayout)))
+}
+
+// TextEncoder implements a text/plain encoder that renders the WireError using the specified layout.
+// The layout is a text/template that gets an ErrorTemplateData as data.
+// Register it with a charset, so the Content-Type is sent with it (see WrapEncodeFunc).
+//
+// Example:
+//
+//	encoder, err := TextEncoder("{{.StatusCode}} {{.Message}}\n")
+//	...
+//	handler.Encoders().Register(WrapEncodeFunc(encoder, "text/plain").WithCharset("utf-8"))
+func TextEncoder(layout string) (EncodeFunc, error) {
+	tmpl, err := template.New("text").Parse(layout)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse layout")
+	}
+	return textEncoder(tmpl), nil
+}
+
+func textEncoder(tmpl *template.Template) EncodeFunc {
+	return func(w http.ResponseWriter, r *http.Request, e *WireError) error {
+		// render into a buffer first, so a failing layout does not result in a partial response
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, newErrorTemplateData(e)); err != nil {
+			return errors.Wrap(err, "unable to execute layout")
+		}
+		_, err := buf.WriteTo(w)
+		return err
+	}
+}
@@ -0,0 +1,78 @@
+package httphandler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Eun/go-hit"
+
+	"github.com/pkg/errors"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/talon-one/go-httphandler"
+)
+
+func TestTextEncoder(t *testing.T) {
+	h := httphandler.New(nil)
+	require.NoError(t, h.SetRequestUUIDFunc(func() string {
+		return "0123456789"
+	}))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		return &httphandler.HandlerError{
+			StatusCode:  http.StatusServiceUnavailable,
+			PublicError: errors.New("database is not reachable"),
+		}
+	}))
+
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	t.Run("default layout", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(s.URL),
+			hit.Send().Headers("Accept").Add("text/plain, application/json;q=0.9, */*;q=0.1"),
+			hit.Expect().Status().Equal(http.StatusServiceUnavailable),
+			hit.Expect().Headers("Content-Type").Equal("text/plain; charset=utf-8"),
+			hit.Expect().Body().String().Equal("503 Service Unavailable: database is not reachable (RequestUUID: 0123456789)\n"),
+		)
+	})
+
+	t.Run("wildcard prefers json", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(s.URL),
+			hit.Send().Headers("Accept").Add("*/*"),
+			hit.Expect().Headers("Content-Type").Equal("application/json"),
+		)
+	})
+
+	t.Run("custom layout", func(t *testing.T) {
+		encoder, err := httphandler.TextEncoder("{{.StatusCode}} {{.Message}}")
+		require.NoError(t, err)
+		require.NoError(t, h.SetEncoder("text/plain", encoder))
+		hit.Test(t,
+			hit.Get(s.URL),
+			hit.Send().Headers("Accept").Add("text/plain"),
+			hit.Expect().Body().String().Equal("503 database is not reachable"),
+		)
+	})
+
+	t.Run("invalid layout", func(t *testing.T) {
+		_, err := httphandler.TextEncoder("{{.StatusCode")
+		require.Error(t, err)
+	})
+}
+
+func TestDefaultTextEncoderCharset(t *testing.T) {
+	for _, h := range []*httphandler.Handler{httphandler.New(nil), httphandler.New(&httphandler.Options{})} {
+		var charset string
+		for _, info := range h.Encoders().List() {
+			if info.MediaTypes[0] == "text/plain" {
+				charset = info.Charset
+			}
+		}
+		require.Equal(t, "utf-8", charset)
+	}
+}
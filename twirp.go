@@ -0,0 +1,151 @@
+package httphandler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// TwirpCode is an error code of the Twirp protocol.
+type TwirpCode string
+
+// The error codes of the Twirp protocol.
+const (
+	TwirpCodeCanceled           TwirpCode = "canceled"
+	TwirpCodeUnknown            TwirpCode = "unknown"
+	TwirpCodeInvalidArgument    TwirpCode = "invalid_argument"
+	TwirpCodeMalformed          TwirpCode = "malformed"
+	TwirpCodeDeadlineExceeded   TwirpCode = "deadline_exceeded"
+	TwirpCodeNotFound           TwirpCode = "not_found"
+	TwirpCodeBadRoute           TwirpCode = "bad_route"
+	TwirpCodeAlreadyExists      TwirpCode = "already_exists"
+	TwirpCodePermissionDenied   TwirpCode = "permission_denied"
+	TwirpCodeUnauthenticated    TwirpCode = "unauthenticated"
+	TwirpCodeResourceExhausted  TwirpCode = "resource_exhausted"
+	TwirpCodeFailedPrecondition TwirpCode = "failed_precondition"
+	TwirpCodeAborted            TwirpCode = "aborted"
+	TwirpCodeOutOfRange         TwirpCode = "out_of_range"
+	TwirpCodeUnimplemented      TwirpCode = "unimplemented"
+	TwirpCodeInternal           TwirpCode = "internal"
+	TwirpCodeUnavailable        TwirpCode = "unavailable"
+	TwirpCodeDataLoss           TwirpCode = "dataloss"
+)
+
+// TwirpRequestUUIDMeta is the meta key that holds the RequestUUID in Twirp errors.
+const TwirpRequestUUIDMeta = "requestUUID"
+
+// TwirpHTTPStatus returns the http status code the Twirp protocol mandates for the code.
+func TwirpHTTPStatus(code TwirpCode) int {
+	switch code {
+	case TwirpCodeCanceled, TwirpCodeDeadlineExceeded:
+		return http.StatusRequestTimeout
+	case TwirpCodeInvalidArgument, TwirpCodeMalformed, TwirpCodeOutOfRange:
+		return http.StatusBadRequest
+	case TwirpCodeNotFound, TwirpCodeBadRoute:
+		return http.StatusNotFound
+	case TwirpCodeAlreadyExists, TwirpCodeAborted:
+		return http.StatusConflict
+	case TwirpCodePermissionDenied:
+		return http.StatusForbidden
+	case TwirpCodeUnauthenticated:
+		return http.StatusUnauthorized
+	case TwirpCodeResourceExhausted:
+		return http.StatusTooManyRequests
+	case TwirpCodeFailedPrecondition:
+		return http.StatusPreconditionFailed
+	case TwirpCodeUnimplemented:
+		return http.StatusNotImplemented
+	case TwirpCodeUnavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// TwirpError is a PublicError that is rendered by TwirpErrorEncoder, other encoders render the Msg.
+type TwirpError struct {
+	// Code is the Twirp error code. If not specified the code will be derived from the http status code.
+	Code TwirpCode
+	// Msg is the error message for the client.
+	Msg string
+	// Meta is additional information about the error.
+	Meta map[string]string
+}
+
+func (e *TwirpError) Error() string {
+	return e.Msg
+}
+
+// NewTwirpError returns a HandlerError with the status code the Twirp protocol mandates for the code and a
+// TwirpError as PublicError.
+//
+// Example:
+//
+//	return NewTwirpError(TwirpCodeNotFound, "user not found", nil)
+func NewTwirpError(code TwirpCode, msg string, meta map[string]string) *HandlerError {
+	return &HandlerError{
+		StatusCode: TwirpHTTPStatus(code),
+		PublicError: &TwirpError{
+			Code: code,
+			Msg:  msg,
+			Meta: meta,
+		},
+	}
+}
+
+// twirpCodeFromHTTPStatus returns the Twirp error code for the http status code, see RPCCodeFromHTTPStatus.
+func twirpCodeFromHTTPStatus(statusCode int) TwirpCode {
+	switch code := RPCCodeFromHTTPStatus(statusCode); code {
+	case RPCCodeOK:
+		return TwirpCodeUnknown
+	case RPCCodeDataLoss:
+		return TwirpCodeDataLoss
+	default:
+		return TwirpCode(connectCodes[code])
+	}
+}
+
+// TwirpErrorEncoder implements an encoder for the error JSON of the Twirp protocol
+// (e.g. {"code":"not_found","msg":"user not found","meta":{"requestUUID":"..."}}).
+// It should be registered for "application/json" (and as fallback encoder) if the handlers serve Twirp clients.
+// If the PublicError is a *TwirpError its code, message and meta are used, otherwise the code is derived from the
+// status code and the error message is used as message. Use NewTwirpError to send the status code that fits the code.
+// The RequestUUID is added to the meta, see TwirpRequestUUIDMeta.
+//
+// Example:
+//
+//	handler.SetEncoder("application/json", TwirpErrorEncoder())
+//	handler.SetFallbackEncoder("application/json", TwirpErrorEncoder())
+func TwirpErrorEncoder() EncodeFunc {
+	return func(w http.ResponseWriter, r *http.Request, e *WireError) error {
+		var twirpError TwirpError
+		var te *TwirpError
+		if errors.As(e.Error, &te) {
+			twirpError = *te
+		} else if e.Error != nil {
+			twirpError.Msg = e.Error.Error()
+		}
+		if twirpError.Code == "" {
+			twirpError.Code = twirpCodeFromHTTPStatus(e.StatusCode)
+		}
+
+		meta := make(map[string]string, len(twirpError.Meta)+1)
+		for key, value := range twirpError.Meta {
+			meta[key] = value
+		}
+		if e.RequestUUID != "" {
+			meta[TwirpRequestUUIDMeta] = e.RequestUUID
+		}
+
+		return json.NewEncoder(w).Encode(struct {
+			Code TwirpCode         `json:"code"`
+			Msg  string            `json:"msg"`
+			Meta map[string]string `json:"meta,omitempty"`
+		}{
+			Code: twirpError.Code,
+			Msg:  twirpError.Msg,
+			Meta: meta,
+		})
+	}
+}
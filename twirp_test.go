@@ -0,0 +1,72 @@
+package httphandler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Eun/go-hit"
+
+	"github.com/pkg/errors"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/talon-one/go-httphandler"
+)
+
+func TestTwirpHTTPStatus(t *testing.T) {
+	require.Equal(t, http.StatusRequestTimeout, httphandler.TwirpHTTPStatus(httphandler.TwirpCodeCanceled))
+	require.Equal(t, http.StatusBadRequest, httphandler.TwirpHTTPStatus(httphandler.TwirpCodeMalformed))
+	require.Equal(t, http.StatusNotFound, httphandler.TwirpHTTPStatus(httphandler.TwirpCodeBadRoute))
+	require.Equal(t, http.StatusPreconditionFailed, httphandler.TwirpHTTPStatus(httphandler.TwirpCodeFailedPrecondition))
+	require.Equal(t, http.StatusInternalServerError, httphandler.TwirpHTTPStatus(httphandler.TwirpCodeDataLoss))
+}
+
+func TestTwirpErrorEncoder(t *testing.T) {
+	h := httphandler.New(nil)
+	require.NoError(t, h.SetRequestUUIDFunc(func() string {
+		return "0123456789"
+	}))
+	require.NoError(t, h.SetEncoder("application/json", httphandler.TwirpErrorEncoder()))
+	require.NoError(t, h.SetFallbackEncoder("application/json", httphandler.TwirpErrorEncoder()))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/twirp", h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		return httphandler.NewTwirpError(httphandler.TwirpCodePermissionDenied, "access denied", map[string]string{
+			"scope": "admin",
+		})
+	}))
+	mux.HandleFunc("/error", h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		return &httphandler.HandlerError{
+			StatusCode:  http.StatusServiceUnavailable,
+			PublicError: errors.New("database is not reachable"),
+		}
+	}))
+
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	t.Run("twirp error", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(hit.JoinURL(s.URL, "twirp")),
+			hit.Expect().Status().Equal(http.StatusForbidden),
+			hit.Expect().Headers("Content-Type").Equal("application/json"),
+			hit.Expect().Body().JSON().Equal(map[string]interface{}{
+				"code": "permission_denied",
+				"msg":  "access denied",
+				"meta": map[string]interface{}{
+					"scope":       "admin",
+					"requestUUID": "0123456789",
+				},
+			}),
+		)
+	})
+
+	t.Run("plain error", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(hit.JoinURL(s.URL, "error")),
+			hit.Expect().Status().Equal(http.StatusServiceUnavailable),
+			hit.Expect().Body().JSON().JQ(".code").Equal("unavailable"),
+			hit.Expect().Body().JSON().JQ(".msg").Equal("database is not reachable"),
+		)
+	})
+}
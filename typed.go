@@ -0,0 +1,64 @@
+package httphandler
+
+import (
+	"context"
+	"net/http"
+)
+
+// TypedHandlerFunc is a handler func that gets the decoded request body and returns the value that should be sent to
+// the client, see TypedHandleFunc.
+type TypedHandlerFunc[Req, Resp any] func(ctx context.Context, req Req) (Resp, *HandlerError)
+
+// TypedHandleFunc wraps a TypedHandlerFunc, so the handler only deals with its request and response types:
+// the request body is decoded into Req using Handler.Decode, the handler is called with the panic protection of
+// HandleFunc and the returned Resp is sent with successStatus using Handler.Respond.
+// Errors (returned by the handler, or occurring while decoding and encoding) are sent like in HandleFunc.
+// Requests without a body (e.g. GET requests) are not decoded, the handler gets the zero value of Req.
+// If successStatus is 0 http.StatusOK will be used, for http.StatusNoContent no body is sent.
+// If h is nil the DefaultHandler will be used.
+//
+// The context is the context of the request, use GetRequestUUIDFromContext to get the request uuid.
+//
+// Example:
+//
+//	http.HandleFunc("/users", TypedHandleFunc(handler, http.StatusCreated,
+//	    func(ctx context.Context, req CreateUserRequest) (*User, *HandlerError) {
+//	        user, err := createUser(ctx, req)
+//	        if err != nil {
+//	            return nil, &HandlerError{StatusCode: http.StatusConflict, PublicError: errors.New("user exists")}
+//	        }
+//	        return user, nil
+//	    }))
+func TypedHandleFunc[Req, Resp any](h *Handler, successStatus int, handler TypedHandlerFunc[Req, Resp]) http.HandlerFunc {
+	if h == nil {
+		h = DefaultHandler
+	}
+	if successStatus == 0 {
+		successStatus = http.StatusOK
+	}
+	return h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *HandlerError {
+		var req Req
+		if hasBody(r) {
+			if err := h.Decode(r, &req); err != nil {
+				return err
+			}
+		}
+		resp, err := handler(r.Context(), req)
+		if err != nil {
+			return err
+		}
+		if successStatus == http.StatusNoContent {
+			w.WriteHeader(successStatus)
+			return nil
+		}
+		return h.Respond(w, r, successStatus, resp)
+	})
+}
+
+// hasBody reports whether the request has a body that should be decoded.
+func hasBody(r *http.Request) bool {
+	if r.Body == nil || r.Body == http.NoBody {
+		return false
+	}
+	return r.ContentLength != 0 || len(r.TransferEncoding) > 0
+}
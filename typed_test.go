@@ -0,0 +1,120 @@
+package httphandler_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Eun/go-hit"
+
+	"github.com/pkg/errors"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/talon-one/go-httphandler"
+)
+
+type typedRequest struct {
+	Name string `json:"name"`
+}
+
+type typedResponse struct {
+	Greeting    string `json:"greeting"`
+	RequestUUID string `json:"requestUUID"`
+}
+
+func TestTypedHandleFunc(t *testing.T) {
+	h := httphandler.New(nil)
+	require.NoError(t, h.SetRequestUUIDFunc(func() string {
+		return "0123456789"
+	}))
+	greet := func(ctx context.Context, req typedRequest) (*typedResponse, *httphandler.HandlerError) {
+		switch req.Name {
+		case "":
+			return &typedResponse{Greeting: "Hello", RequestUUID: httphandler.GetRequestUUIDFromContext(ctx)}, nil
+		case "nobody":
+			return nil, &httphandler.HandlerError{
+				StatusCode:  http.StatusNotFound,
+				PublicError: errors.New("user not found"),
+			}
+		case "panic":
+			panic("oops")
+		default:
+			return &typedResponse{Greeting: "Hello " + req.Name, RequestUUID: httphandler.GetRequestUUIDFromContext(ctx)}, nil
+		}
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/greet", httphandler.TypedHandleFunc(h, http.StatusCreated, greet))
+	mux.Handle("/default", httphandler.TypedHandleFunc(nil, 0, greet))
+	mux.Handle("/forget", httphandler.TypedHandleFunc(h, http.StatusNoContent,
+		func(ctx context.Context, req typedRequest) (struct{}, *httphandler.HandlerError) {
+			return struct{}{}, nil
+		}))
+
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	t.Run("decode and respond", func(t *testing.T) {
+		hit.Test(t,
+			hit.Post(hit.JoinURL(s.URL, "greet")),
+			hit.Send().Headers("Content-Type").Add("application/json"),
+			hit.Send().Body().String(`{"name":"Joe"}`),
+			hit.Expect().Status().Equal(http.StatusCreated),
+			hit.Expect().Headers("Content-Type").Equal("application/json"),
+			hit.Expect().Body().JSON().Equal(map[string]interface{}{
+				"greeting":    "Hello Joe",
+				"requestUUID": "0123456789",
+			}),
+		)
+	})
+
+	t.Run("no body", func(t *testing.T) {
+		hit.Test(t,
+			hit.Get(hit.JoinURL(s.URL, "default")),
+			hit.Send().Headers("Accept").Add("application/xml"),
+			hit.Expect().Status().Equal(http.StatusOK),
+			hit.Expect().Headers("Content-Type").Equal("application/xml"),
+			hit.Expect().Body().String().Contains("<Greeting>Hello</Greeting>"),
+		)
+	})
+
+	t.Run("handler error", func(t *testing.T) {
+		hit.Test(t,
+			hit.Post(hit.JoinURL(s.URL, "greet")),
+			hit.Send().Headers("Content-Type").Add("application/json"),
+			hit.Send().Body().String(`{"name":"nobody"}`),
+			hit.Expect().Status().Equal(http.StatusNotFound),
+			hit.Expect().Body().JSON().JQ(".Error").Equal("user not found"),
+		)
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		hit.Test(t,
+			hit.Post(hit.JoinURL(s.URL, "greet")),
+			hit.Send().Headers("Content-Type").Add("application/json"),
+			hit.Send().Body().String(`{"name":"panic"}`),
+			hit.Expect().Status().Equal(http.StatusInternalServerError),
+			hit.Expect().Body().JSON().JQ(".Error").Equal("unknown error"),
+		)
+	})
+
+	t.Run("unsupported media type", func(t *testing.T) {
+		hit.Test(t,
+			hit.Post(hit.JoinURL(s.URL, "greet")),
+			hit.Send().Headers("Content-Type").Add("image/png"),
+			hit.Send().Body().String("..."),
+			hit.Expect().Status().Equal(http.StatusUnsupportedMediaType),
+		)
+	})
+
+	t.Run("no content", func(t *testing.T) {
+		hit.Test(t,
+			hit.Post(hit.JoinURL(s.URL, "forget")),
+			hit.Send().Headers("Content-Type").Add("application/json"),
+			hit.Send().Body().String(`{"name":"Joe"}`),
+			hit.Expect().Status().Equal(http.StatusNoContent),
+			hit.Expect().Body().String().Equal(""),
+		)
+	})
+}
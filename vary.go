@@ -0,0 +1,32 @@
+package httphandler
+
+import (
+	"net/http"
+	"strings"
+)
+
+// addVary adds the specified request header names to the Vary header.
+// Existing values are kept, duplicates are removed and a Vary of "*" stays "*" since it already covers every header.
+func addVary(header http.Header, fields ...string) {
+	if len(fields) == 0 {
+		return
+	}
+
+	var values []string
+	seen := make(map[string]struct{})
+	for _, value := range append(header.Values("Vary"), fields...) {
+		for _, field := range splitHeaderList(value) {
+			if field == "*" {
+				header.Set("Vary", "*")
+				return
+			}
+			field = http.CanonicalHeaderKey(field)
+			if _, ok := seen[field]; ok {
+				continue
+			}
+			seen[field] = struct{}{}
+			values = append(values, field)
+		}
+	}
+	header.Set("Vary", strings.Join(values, ", "))
+}
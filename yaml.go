@@ -0,0 +1,99 @@
+package httphandler
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// YAMLContentTypes are the media types YAML is served with. The YAML encoder is not part of the default Encoders,
+// use SetYAMLEncoder to register an encoder for all of them.
+var YAMLContentTypes = []string{"application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml"}
+
+// DefaultYAMLEncoder implements a YAML encoder.
+// Like DefaultJSONEncoder it marshals the Error first (so a custom PublicError can implement yaml.Marshaler) and uses
+// the Error() function if the marshaled error is empty.
+func DefaultYAMLEncoder() EncodeFunc {
+	return func(w http.ResponseWriter, r *http.Request, e *WireError) error {
+		errToSend := struct {
+			StatusCode  *int        `yaml:"StatusCode"`
+			Error       interface{} `yaml:"Error"`
+			RequestUUID *string     `yaml:"RequestUUID"`
+		}{
+			StatusCode:  &e.StatusCode,
+			RequestUUID: &e.RequestUUID,
+		}
+
+		// marshal the Error before everything else
+		node, err := marshalYAMLError(e.Error)
+		if err != nil {
+			return errors.Wrap(err, "unable to encode error")
+		}
+
+		// if the error message is empty use the Error() function
+		switch {
+		case e.Error == nil:
+			// send Error: null like the JSON encoder
+		case node == nil || isEmptyYAMLNode(node):
+			errToSend.Error = e.Error.Error()
+		default:
+			errToSend.Error = node
+		}
+
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2) //nolint:gomnd // two spaces are the common indentation for YAML
+		if err := enc.Encode(errToSend); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+}
+
+// marshalYAMLError marshals the error into a yaml.Node.
+// yaml.v3 panics on some types it cannot reflect on (e.g. structs that embed an unexported pointer, like the errors of
+// github.com/pkg/errors), these errors are reported as not marshalable by returning a nil node. Other panics (e.g. of
+// a MarshalYAML function) are not recovered.
+func marshalYAMLError(e error) (node *yaml.Node, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if !isReflectPanic(r) {
+				panic(r)
+			}
+			node, err = nil, nil
+		}
+	}()
+	node = new(yaml.Node)
+	if err := node.Encode(e); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// isReflectPanic reports whether the recovered value is a panic of the reflect package.
+func isReflectPanic(r interface{}) bool {
+	switch v := r.(type) {
+	case *reflect.ValueError:
+		return true
+	case string:
+		return strings.HasPrefix(v, "reflect")
+	default:
+		return false
+	}
+}
+
+// isEmptyYAMLNode reports whether the node is null or an empty mapping.
+func isEmptyYAMLNode(node *yaml.Node) bool {
+	switch node.Kind {
+	case 0:
+		return true
+	case yaml.MappingNode:
+		return len(node.Content) == 0
+	case yaml.ScalarNode:
+		return node.Tag == "!!null"
+	default:
+		return false
+	}
+}
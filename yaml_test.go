@@ -0,0 +1,104 @@
+package httphandler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Eun/go-hit"
+
+	"github.com/pkg/errors"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/talon-one/go-httphandler"
+)
+
+type yamlError struct {
+	Title string
+}
+
+func (e yamlError) Error() string {
+	return e.Title
+}
+
+func (e yamlError) MarshalYAML() (interface{}, error) {
+	return map[string]string{"title": e.Title}, nil
+}
+
+type panickingYAMLError struct{}
+
+func (panickingYAMLError) Error() string {
+	return "panicking"
+}
+
+func (panickingYAMLError) MarshalYAML() (interface{}, error) {
+	panic("boom")
+}
+
+func TestDefaultYAMLEncoder(t *testing.T) {
+	encode := func(err error) string {
+		w := httptest.NewRecorder()
+		require.NoError(t, httphandler.DefaultYAMLEncoder()(w, nil, &httphandler.WireError{
+			StatusCode:  http.StatusBadRequest,
+			Error:       err,
+			RequestUUID: "0123456789",
+		}))
+		return w.Body.String()
+	}
+
+	t.Run("error without fields", func(t *testing.T) {
+		require.Equal(t, "StatusCode: 400\nError: some error\nRequestUUID: \"0123456789\"\n", encode(errors.New("some error")))
+	})
+
+	t.Run("yaml.Marshaler", func(t *testing.T) {
+		require.Equal(t, "StatusCode: 400\nError:\n  title: some error\nRequestUUID: \"0123456789\"\n",
+			encode(yamlError{Title: "some error"}))
+	})
+
+	t.Run("nil error", func(t *testing.T) {
+		require.Equal(t, "StatusCode: 400\nError: null\nRequestUUID: \"0123456789\"\n", encode(nil))
+	})
+
+	t.Run("panicking yaml.Marshaler", func(t *testing.T) {
+		require.PanicsWithValue(t, "boom", func() {
+			encode(panickingYAMLError{})
+		})
+	})
+
+	t.Run("struct", func(t *testing.T) {
+		require.Equal(t, "StatusCode: 400\nError:\n  title: some error\n  details: some details\nRequestUUID: \"0123456789\"\n",
+			encode(xmlError{Title: "some error", Details: "some details"}))
+	})
+}
+
+func TestSetYAMLEncoder(t *testing.T) {
+	h := httphandler.New(nil)
+	require.EqualError(t, h.SetYAMLEncoder(nil), "encoder cannot be nil")
+	require.NoError(t, h.SetYAMLEncoder(httphandler.DefaultYAMLEncoder()))
+	h.SetFormatSources(httphandler.FormatFromQuery("format"))
+
+	s := httptest.NewServer(h.HandleFunc(func(w http.ResponseWriter, r *http.Request) *httphandler.HandlerError {
+		return &httphandler.HandlerError{
+			StatusCode:  http.StatusNotFound,
+			PublicError: errors.New("user not found"),
+		}
+	}))
+	defer s.Close()
+
+	for _, contentType := range httphandler.YAMLContentTypes {
+		hit.Test(t,
+			hit.Get(s.URL),
+			hit.Send().Headers("Accept").Add(contentType),
+			hit.Expect().Status().Equal(http.StatusNotFound),
+			hit.Expect().Headers("Content-Type").Equal(contentType),
+			hit.Expect().Body().String().Contains("Error: user not found\n"),
+		)
+	}
+
+	hit.Test(t,
+		hit.Get(s.URL+"?format=yaml"),
+		hit.Expect().Headers("Content-Type").Equal("application/yaml"),
+		hit.Expect().Body().String().Contains("StatusCode: 404\n"),
+	)
+}